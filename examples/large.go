@@ -20,7 +20,7 @@ func main() {
 	start := time.Now()
 
 	source, _ := ioutil.ReadFile(input)
-	image, err := magick.NewFromBlob(source, "jpg")
+	image, err := magick.NewFromBlobWithOptions(source, "jpg", &magick.LoadOptions{SizeHint: "2000x2000"})
 	log.Printf("Loading image took %v\n", time.Now().Sub(start))
 	start = time.Now()
 	if err != nil {
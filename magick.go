@@ -14,6 +14,7 @@ package magick
 #cgo pkg-config: MagickCore
 #include <stdio.h>
 #include <stdlib.h>
+#include <stdint.h>
 #include <string.h>
 #include <assert.h>
 #include <magick/MagickCore.h>
@@ -23,6 +24,46 @@ void SetImageInfoFilename(ImageInfo *image_info, char *filename)
   (void) CopyMagickString(image_info->filename,filename,MaxTextExtent);
 }
 
+void SetImageInfoSize(ImageInfo *image_info, char *size)
+{
+  (void) CloneString(&image_info->size, size);
+}
+
+void SetImageInfoScenes(ImageInfo *image_info, const size_t scene, const size_t number_scenes)
+{
+  char scenes[MaxTextExtent];
+  image_info->scene = scene;
+  image_info->number_scenes = number_scenes;
+  (void) FormatLocaleString(scenes, MaxTextExtent, "%.20g-%.20g", (double) scene,
+    (double) (scene + number_scenes - 1));
+  (void) CloneString(&image_info->scenes, scenes);
+}
+
+void SetImageInfoDensity(ImageInfo *image_info, char *density)
+{
+  (void) CloneString(&image_info->density, density);
+}
+
+void SetImageInfoSamplingFactor(ImageInfo *image_info, char *sampling_factor)
+{
+  (void) CloneString(&image_info->sampling_factor, sampling_factor);
+}
+
+MagickStatusType ParseGravityRegion(Image *image, char *geometry, const GravityType gravity,
+  RectangleInfo *region_info, ExceptionInfo *exception)
+{
+  MagickStatusType flags;
+  Image *gravity_image;
+  gravity_image = CloneImage(image, 0, 0, MagickTrue, exception);
+  if (gravity_image == (Image *) NULL) {
+    return NoValue;
+  }
+  gravity_image->gravity = gravity;
+  flags = ParseGravityGeometry(gravity_image, geometry, region_info, exception);
+  gravity_image = DestroyImage(gravity_image);
+  return flags;
+}
+
 MagickBooleanType CheckException(ExceptionInfo *exception)
 {
   register const ExceptionInfo
@@ -53,6 +94,19 @@ MagickBooleanType CheckException(ExceptionInfo *exception)
   return haserr == 0 ? MagickFalse : MagickTrue;
 }
 
+MagickBooleanType GetEncoderSupport(char *format)
+{
+  ExceptionInfo *exception;
+  const MagickInfo *magick_info;
+  exception = AcquireExceptionInfo();
+  magick_info = GetMagickInfo(format, exception);
+  DestroyExceptionInfo(exception);
+  if ((magick_info == (const MagickInfo *) NULL) || (GetImageEncoder(magick_info) == NULL)) {
+    return MagickFalse;
+  }
+  return MagickTrue;
+}
+
 MagickBooleanType GetBlobSupport(ImageInfo *image_info)
 {
   ExceptionInfo *exception;
@@ -87,6 +141,80 @@ Image *ReadImageFromBlob(ImageInfo *image_info, void *blob, size_t length)
   return image;
 }
 
+MagickBooleanType DetectBlobFormat(ImageInfo *image_info, void *blob, size_t length, ExceptionInfo *exception)
+{
+  MagickBooleanType status;
+  SetImageInfoBlob(image_info, blob, length);
+  status = SetImageInfo(image_info, 0, exception);
+  SetImageInfoBlob(image_info, (void *) NULL, 0);
+  return status;
+}
+
+void ResetImageListPage(Image *images)
+{
+  Image *image;
+  for (image = GetFirstImageInList(images); image != (Image *) NULL; image = GetNextImageInList(image))
+    (void) ResetImagePage(image, "0x0+0+0");
+}
+
+extern ssize_t magickStreamRead(unsigned char *, size_t, void *);
+extern ssize_t magickStreamWrite(unsigned char *, size_t, void *);
+extern MagickOffsetType magickStreamSeek(MagickOffsetType, int, void *);
+extern MagickOffsetType magickStreamTell(void *);
+
+static ssize_t StreamReader(unsigned char *data, const size_t length, void *user_data)
+{
+  return magickStreamRead(data, length, user_data);
+}
+
+static ssize_t StreamWriter(unsigned char *data, const size_t length, void *user_data)
+{
+  return magickStreamWrite(data, length, user_data);
+}
+
+static MagickOffsetType StreamSeeker(const MagickOffsetType offset, const int whence, void *user_data)
+{
+  return magickStreamSeek(offset, whence, user_data);
+}
+
+static MagickOffsetType StreamTeller(void *user_data)
+{
+  return magickStreamTell(user_data);
+}
+
+Image *ReadImageFromStream(ImageInfo *image_info, uintptr_t handle, int seekable, ExceptionInfo *exception)
+{
+  Image *image;
+  CustomStreamInfo *custom_stream;
+  custom_stream = AcquireCustomStreamInfo(exception);
+  SetCustomStreamData(custom_stream, (void *) handle);
+  SetCustomStreamReader(custom_stream, StreamReader);
+  if (seekable) {
+    SetCustomStreamSeeker(custom_stream, StreamSeeker);
+    SetCustomStreamTeller(custom_stream, StreamTeller);
+  }
+  SetImageInfoCustomStream(image_info, custom_stream);
+  image = CustomStreamToImage(image_info, exception);
+  SetImageInfoCustomStream(image_info, (CustomStreamInfo *) NULL);
+  DestroyCustomStreamInfo(custom_stream);
+  return image;
+}
+
+void WriteImageToStream(ImageInfo *image_info, Image *image, uintptr_t handle, int seekable, ExceptionInfo *exception)
+{
+  CustomStreamInfo *custom_stream;
+  custom_stream = AcquireCustomStreamInfo(exception);
+  SetCustomStreamData(custom_stream, (void *) handle);
+  SetCustomStreamWriter(custom_stream, StreamWriter);
+  if (seekable) {
+    SetCustomStreamSeeker(custom_stream, StreamSeeker);
+    SetCustomStreamTeller(custom_stream, StreamTeller);
+  }
+  SetImageInfoCustomStream(image_info, custom_stream);
+  ImageToCustomStream(image_info, image, exception);
+  SetImageInfoCustomStream(image_info, (CustomStreamInfo *) NULL);
+  DestroyCustomStreamInfo(custom_stream);
+}
 
 Image *AddShadowToImage(Image *image, char *colorname, const double opacity,
   const double sigma,const ssize_t x_offset,const ssize_t y_offset,
@@ -123,6 +251,179 @@ Image *FillBackgroundColor(Image *image, char *colorname, ExceptionInfo *excepti
     return image;
 }
 
+Image *ExtentWithGravity(Image *image, const size_t width, const size_t height, const ssize_t x_offset,
+  const ssize_t y_offset, const GravityType gravity, char *colorname, ExceptionInfo *exception)
+{
+  RectangleInfo geometry;
+  Image *background_image, *new_image;
+  background_image = CloneImage(image, 0, 0, MagickTrue, exception);
+  if (background_image == (Image *) NULL) {
+    return background_image;
+  }
+  if (QueryColorDatabase(colorname, &background_image->background_color, exception) == MagickFalse) {
+    background_image = DestroyImage(background_image);
+    return (Image *) NULL;
+  }
+  if ((background_image->background_color.opacity != OpaqueOpacity) && (background_image->matte == MagickFalse)) {
+    (void) SetImageAlphaChannel(background_image, OpaqueAlphaChannel);
+  }
+  geometry.width = width;
+  geometry.height = height;
+  geometry.x = x_offset;
+  geometry.y = y_offset;
+  GravityAdjustGeometry(image->columns, image->rows, gravity, &geometry);
+  new_image = ExtentImage(background_image, &geometry, exception);
+  background_image = DestroyImage(background_image);
+  return new_image;
+}
+
+Image *ThumbnailImageWithFilter(Image *image, const size_t columns, const size_t rows, const FilterTypes filter,
+  const double blur, ExceptionInfo *exception)
+{
+  Image *filtered_image, *new_image;
+  filtered_image = CloneImage(image, 0, 0, MagickTrue, exception);
+  if (filtered_image == (Image *) NULL) {
+    return filtered_image;
+  }
+  filtered_image->filter = filter;
+  filtered_image->blur = blur;
+  new_image = ThumbnailImage(filtered_image, columns, rows, exception);
+  filtered_image = DestroyImage(filtered_image);
+  if (new_image != (Image *) NULL) {
+    new_image->filter = image->filter;
+    new_image->blur = image->blur;
+  }
+  return new_image;
+}
+
+Image *RotateWithBackground(Image *image, const double degrees, char *colorname, ExceptionInfo *exception)
+{
+  Image *background_image, *new_image;
+  background_image = CloneImage(image, 0, 0, MagickTrue, exception);
+  if (background_image == (Image *) NULL) {
+    return background_image;
+  }
+  if (QueryColorDatabase(colorname, &background_image->background_color, exception) == MagickFalse) {
+    background_image = DestroyImage(background_image);
+    return (Image *) NULL;
+  }
+  if ((background_image->background_color.opacity != OpaqueOpacity) && (background_image->matte == MagickFalse)) {
+    (void) SetImageAlphaChannel(background_image, OpaqueAlphaChannel);
+  }
+  new_image = RotateImage(background_image, degrees, exception);
+  background_image = DestroyImage(background_image);
+  if (new_image != (Image *) NULL) {
+    (void) ResetImagePage(new_image, "0x0+0+0");
+  }
+  return new_image;
+}
+
+Image *TrimImageWithFuzz(Image *image, const double fuzz, RectangleInfo *bounds, ExceptionInfo *exception)
+{
+  Image *new_image;
+  double previous_fuzz;
+  previous_fuzz = image->fuzz;
+  image->fuzz = fuzz * QuantumRange / 100.0;
+  *bounds = GetImageBoundingBox(image, exception);
+  image->fuzz = previous_fuzz;
+  if ((bounds->width == 0) || (bounds->height == 0)) {
+    return (Image *) NULL;
+  }
+  new_image = CropImage(image, bounds, exception);
+  if (new_image != (Image *) NULL) {
+    (void) ResetImagePage(new_image, "0x0+0+0");
+  }
+  return new_image;
+}
+
+MagickBooleanType CompositeWithGravity(Image *image, const CompositeOperator compose, Image *composite_image,
+  const GravityType gravity, const ssize_t x_offset, const ssize_t y_offset, char *args, ExceptionInfo *exception)
+{
+  RectangleInfo geometry;
+  MagickBooleanType status;
+  geometry.width = composite_image->columns;
+  geometry.height = composite_image->rows;
+  geometry.x = x_offset;
+  geometry.y = y_offset;
+  GravityAdjustGeometry(image->columns, image->rows, gravity, &geometry);
+  if (args != (char *) NULL) {
+    (void) SetImageArtifact(image, "compose:args", args);
+  }
+  ClearMagickException(&image->exception);
+  status = CompositeImage(image, compose, composite_image, geometry.x, geometry.y);
+  InheritException(exception, &image->exception);
+  if (args != (char *) NULL) {
+    (void) DeleteImageArtifact(image, "compose:args");
+  }
+  return status;
+}
+
+DrawInfo *AcquireTextDrawInfo(ImageInfo *image_info, char *text, char *font, const double pointsize,
+  char *fill, char *stroke, const double stroke_width, const GravityType gravity, char *geometry,
+  ExceptionInfo *exception)
+{
+  DrawInfo *draw_info;
+  draw_info = CloneDrawInfo(image_info, (DrawInfo *) NULL);
+  (void) CloneString(&draw_info->text, text);
+  if (font != (char *) NULL) {
+    (void) CloneString(&draw_info->font, font);
+  }
+  if (pointsize > 0.0) {
+    draw_info->pointsize = pointsize;
+  }
+  if ((fill != (char *) NULL) && (QueryColorDatabase(fill, &draw_info->fill, exception) == MagickFalse)) {
+    return DestroyDrawInfo(draw_info);
+  }
+  if ((stroke != (char *) NULL) && (QueryColorDatabase(stroke, &draw_info->stroke, exception) == MagickFalse)) {
+    return DestroyDrawInfo(draw_info);
+  }
+  if (stroke_width > 0.0) {
+    draw_info->stroke_width = stroke_width;
+  }
+  draw_info->gravity = gravity;
+  if (geometry != (char *) NULL) {
+    (void) CloneString(&draw_info->geometry, geometry);
+  }
+  return draw_info;
+}
+
+Image *OrientImage(Image *image, ExceptionInfo *exception)
+{
+  Image *new_image;
+  switch (image->orientation) {
+    case TopRightOrientation:
+      new_image = FlopImage(image, exception);
+      break;
+    case BottomRightOrientation:
+      new_image = RotateImage(image, 180.0, exception);
+      break;
+    case BottomLeftOrientation:
+      new_image = FlipImage(image, exception);
+      break;
+    case LeftTopOrientation:
+      new_image = TransposeImage(image, exception);
+      break;
+    case RightTopOrientation:
+      new_image = RotateImage(image, 90.0, exception);
+      break;
+    case RightBottomOrientation:
+      new_image = TransverseImage(image, exception);
+      break;
+    case LeftBottomOrientation:
+      new_image = RotateImage(image, 270.0, exception);
+      break;
+    default:
+      new_image = CloneImage(image, 0, 0, MagickTrue, exception);
+      break;
+  }
+  if (new_image == (Image *) NULL) {
+    return new_image;
+  }
+  new_image->orientation = TopLeftOrientation;
+  (void) SetImageProperty(new_image, "exif:Orientation", "1");
+  return new_image;
+}
+
 Image *SeparateAlphaChannel(Image *image, ExceptionInfo *exception){
   Image *new_image;
   new_image = CloneImage(image, 0, 0, MagickTrue, exception);
@@ -144,9 +445,16 @@ Image *Negate(Image *image, ExceptionInfo *exception){
 */
 import "C"
 import (
+	"image"
+	"image/draw"
+	"io"
 	"io/ioutil"
 	"math"
 	"os"
+	"path/filepath"
+	"runtime/cgo"
+	"sort"
+	"strconv"
 	"strings"
 	"unsafe"
 )
@@ -167,9 +475,427 @@ type MagickImage struct {
 	ImageInfo (*C.ImageInfo)
 }
 
+// A wrapper around a list of IM Images, such as the frames of a GIF
+// or the pages of a PDF
+type MagickImageList struct {
+	Image     (*C.Image)
+	ImageInfo (*C.ImageInfo)
+}
+
 // Geometry is usually defined as a string of WxH+X+Y
 type MagickGeometry struct {
 	Width, Height, Xoffset, Yoffset int
+	Flags                           GeometryFlags
+}
+
+// Gravity defines where an image or region is placed relative to another
+type Gravity int
+
+const (
+	NorthWestGravity Gravity = C.NorthWestGravity
+	NorthGravity     Gravity = C.NorthGravity
+	NorthEastGravity Gravity = C.NorthEastGravity
+	WestGravity      Gravity = C.WestGravity
+	CenterGravity    Gravity = C.CenterGravity
+	EastGravity      Gravity = C.EastGravity
+	SouthWestGravity Gravity = C.SouthWestGravity
+	SouthGravity     Gravity = C.SouthGravity
+	SouthEastGravity Gravity = C.SouthEastGravity
+)
+
+// DisposeType defines what happens to a frame of an animation before the next frame is shown
+type DisposeType int
+
+const (
+	UndefinedDispose  DisposeType = C.UndefinedDispose
+	NoneDispose       DisposeType = C.NoneDispose
+	BackgroundDispose DisposeType = C.BackgroundDispose
+	PreviousDispose   DisposeType = C.PreviousDispose
+)
+
+// CompositeOperator defines how Composite combines the pixels of two images
+type CompositeOperator int
+
+const (
+	OverCompositeOp       CompositeOperator = C.OverCompositeOp
+	MultiplyCompositeOp   CompositeOperator = C.MultiplyCompositeOp
+	ScreenCompositeOp     CompositeOperator = C.ScreenCompositeOp
+	OverlayCompositeOp    CompositeOperator = C.OverlayCompositeOp
+	DarkenCompositeOp     CompositeOperator = C.DarkenCompositeOp
+	LightenCompositeOp    CompositeOperator = C.LightenCompositeOp
+	SoftLightCompositeOp  CompositeOperator = C.SoftLightCompositeOp
+	HardLightCompositeOp  CompositeOperator = C.HardLightCompositeOp
+	DifferenceCompositeOp CompositeOperator = C.DifferenceCompositeOp
+	CopyCompositeOp       CompositeOperator = C.CopyCompositeOp
+	DissolveCompositeOp   CompositeOperator = C.DissolveCompositeOp
+)
+
+// TextOptions control how Annotate renders text and how MeasureText measures it.
+// Zero values use Magick's defaults.
+type TextOptions struct {
+	// Font is the path to a font file (e.g. a TTF) or the name of a font Magick knows
+	Font string
+	// PointSize is the size of the text, 12 by default
+	PointSize float64
+	// Color fills the text, black by default
+	Color string
+	// StrokeColor and StrokeWidth outline the text, no outline by default
+	StrokeColor string
+	StrokeWidth float64
+	// Gravity positions the text on the image, NorthWestGravity by default
+	Gravity Gravity
+	// XOffset and YOffset move the text away from the position given by Gravity
+	XOffset, YOffset int
+	// WrapWidth word wraps the text into lines no wider than this many pixels, 0 does not wrap
+	WrapWidth int
+}
+
+// TextMetrics are the dimensions in pixels of text as measured by MeasureText
+type TextMetrics struct {
+	Width, Height   float64
+	Ascent, Descent float64
+	MaxAdvance      float64
+}
+
+// ThumbnailMode defines how Thumbnail treats images with a different aspect ratio than the thumbnail
+type ThumbnailMode int
+
+const (
+	// FitThumbnail resizes the image to fit inside the thumbnail, so one side may be shorter
+	FitThumbnail ThumbnailMode = iota
+	// FillThumbnail resizes the image to fill the thumbnail and crops the overflow from the center
+	FillThumbnail
+	// PadThumbnail resizes the image to fit inside the thumbnail and pads it to the exact
+	// size with transparency, centered
+	PadThumbnail
+)
+
+// ResizeMethod selects the MagickCore routine ResizeWithOptions uses to resize an image
+type ResizeMethod int
+
+const (
+	// DefaultResize uses AdaptiveResize for small changes in size and ThumbnailResize for
+	// larger reductions, as Resize does
+	DefaultResize ResizeMethod = iota
+	// FilterResize resamples with the chosen filter (ResizeImage)
+	FilterResize
+	// ThumbnailResize samples down first and then resamples with the chosen filter, stripping profiles
+	ThumbnailResize
+	// SampleResize uses point sampling, fast but without any smoothing
+	SampleResize
+	// ScaleResize averages pixels, fast and smooth for large reductions
+	ScaleResize
+	// AdaptiveResize uses mesh interpolation, sharp for small changes in size
+	AdaptiveResize
+	// LiquidResize uses seam carving, requires ImageMagick built with liblqr
+	LiquidResize
+)
+
+// ResizeOptions control how ResizeWithOptions resizes an image
+type ResizeOptions struct {
+	Method ResizeMethod
+	// Filter is the resampling filter used by FilterResize and ThumbnailResize,
+	// e.g. "Lanczos", "Mitchell", "Catrom", "Box"
+	Filter string
+	// Blur scales the filter support, > 1.0 is blurry and < 1.0 is sharp. 0 keeps the default of 1.0
+	Blur float64
+}
+
+// GeometryFlags are the modifiers given after the size in a geometry string
+type GeometryFlags int
+
+const (
+	// GeometryForce (!) resizes to exactly WxH, ignoring the aspect ratio
+	GeometryForce GeometryFlags = 1 << iota
+	// GeometryFill (^) resizes so the image fills WxH, keeping the aspect ratio
+	GeometryFill
+	// GeometryShrinkOnly (>) only resizes images larger than WxH
+	GeometryShrinkOnly
+	// GeometryEnlargeOnly (<) only resizes images smaller than WxH
+	GeometryEnlargeOnly
+	// GeometryPercent (%) treats W and H as percentages of the current size
+	GeometryPercent
+	// GeometryArea (@) resizes so the image has at most W pixels in total
+	GeometryArea
+)
+
+func geometryFlagsFromStatus(status C.MagickStatusType) (flags GeometryFlags) {
+	if status&C.AspectValue != 0 {
+		flags |= GeometryForce
+	}
+	if status&C.MinimumValue != 0 {
+		flags |= GeometryFill
+	}
+	if status&C.GreaterValue != 0 {
+		flags |= GeometryShrinkOnly
+	}
+	if status&C.LessValue != 0 {
+		flags |= GeometryEnlargeOnly
+	}
+	if status&C.PercentValue != 0 {
+		flags |= GeometryPercent
+	}
+	if status&C.AreaValue != 0 {
+		flags |= GeometryArea
+	}
+	return
+}
+
+// MagickPingInfo holds the basic attributes of an image read with Ping or PingBlob
+// without decoding its pixels
+type MagickPingInfo struct {
+	Width, Height int
+	Format        string
+	Colorspace    string
+	Frames        int
+	Orientation   string
+}
+
+// StorageType defines the size and type of each channel value in the raw pixel
+// data used by NewFromPixels and ExportPixels
+type StorageType int
+
+const (
+	// CharPixel stores each channel as an unsigned 8-bit value
+	CharPixel StorageType = iota
+	// ShortPixel stores each channel as an unsigned 16-bit value in native byte order
+	ShortPixel
+	// FloatPixel stores each channel as a 32-bit float in native byte order from 0.0 to 1.0
+	FloatPixel
+)
+
+func (storage StorageType) size() int {
+	switch storage {
+	case ShortPixel:
+		return 2
+	case FloatPixel:
+		return 4
+	}
+	return 1
+}
+
+func (storage StorageType) storageType() C.StorageType {
+	switch storage {
+	case ShortPixel:
+		return C.ShortPixel
+	case FloatPixel:
+		return C.FloatPixel
+	}
+	return C.CharPixel
+}
+
+// LoadOptions control how image data is decoded by NewFromFileWithOptions and
+// NewFromBlobWithOptions. The zero value loads images the same way as NewFromFile.
+type LoadOptions struct {
+	// Scene is the index of the first frame or page to load, starting at 0,
+	// and Scenes the number of frames or pages to load from there. Scenes
+	// of 0 loads every frame, so Scene can only be set together with Scenes,
+	// e.g. Scene 0 and Scenes 1 loads just the first page.
+	Scene, Scenes int
+	// Density is the resolution in DPI used to rasterize vector formats like PDF
+	Density float64
+	// Colorspace is a hint for the colorspace to decode to (e.g. "sRGB", "CMYK", "Gray")
+	Colorspace string
+	// SizeHint is the geometry (e.g. "2000x2000") the image will be resized to after
+	// loading. Decoders that can shrink on load use it to decode at a reduced scale
+	// that is still at least this large, e.g. libjpeg decodes at 1/2, 1/4 or 1/8 size.
+	// Formats that can not shrink on load ignore it. Resize finishes to the exact size.
+	SizeHint string
+}
+
+// apply sets the options on info before an image is read with it
+func (options *LoadOptions) apply(info *C.ImageInfo) (err error) {
+	if options == nil {
+		return nil
+	}
+	if options.Scene < 0 || options.Scenes < 0 {
+		return &MagickError{"error", "", "scene and scenes can not be negative"}
+	}
+	if options.Scene > 0 && options.Scenes == 0 {
+		return &MagickError{"error", "", "scene needs scenes to be set"}
+	}
+	if options.Scenes > 0 {
+		C.SetImageInfoScenes(info, (C.size_t)(options.Scene), (C.size_t)(options.Scenes))
+	}
+	if options.Density < 0 {
+		return &MagickError{"error", "", "density can not be negative"}
+	}
+	if options.Density > 0 {
+		density := strconv.FormatFloat(options.Density, 'f', -1, 64)
+		c_density := C.CString(density + "x" + density)
+		defer C.free(unsafe.Pointer(c_density))
+		C.SetImageInfoDensity(info, c_density)
+	}
+	if options.Colorspace != "" {
+		c_colorspace := C.CString(options.Colorspace)
+		defer C.free(unsafe.Pointer(c_colorspace))
+		colorspace := C.ParseCommandOption(C.MagickColorspaceOptions, C.MagickFalse, c_colorspace)
+		if colorspace < 0 {
+			return &MagickError{"error", "", "unrecognized colorspace " + options.Colorspace}
+		}
+		info.colorspace = (C.ColorspaceType)(colorspace)
+	}
+	if options.SizeHint != "" {
+		c_hint := C.CString(options.SizeHint)
+		defer C.free(unsafe.Pointer(c_hint))
+		if C.IsGeometry(c_hint) != C.MagickTrue {
+			return &MagickError{"error", "", "invalid size hint " + options.SizeHint}
+		}
+		c_option := C.CString("jpeg:size")
+		defer C.free(unsafe.Pointer(c_option))
+		C.SetImageOption(info, c_option, c_hint)
+	}
+	return nil
+}
+
+// EncodeOptions control how ToBlobWith and ToFileWith encode an image. Zero values keep
+// Magick's defaults. Options that do not apply to the output format are rejected.
+type EncodeOptions struct {
+	// Quality from 1 to 100
+	Quality int
+	// Interlace scheme, e.g. "None", "Line" or "Plane" for a progressive JPEG
+	Interlace string
+	// SamplingFactor is the JPEG chroma subsampling, e.g. "4:2:0" or "4:4:4"
+	SamplingFactor string
+	// PNGCompressionLevel is the zlib compression level from 1 to 9
+	PNGCompressionLevel int
+	// PNGFilter is the PNG row filter: "none", "sub", "up", "average", "paeth" or "adaptive"
+	PNGFilter string
+	// WebPLossless switches WebP to lossless encoding
+	WebPLossless bool
+	// WebPMethod trades WebP encoding speed for size from 1 (fast) to 6 (small)
+	WebPMethod int
+	// AutoOrient applies the EXIF orientation to the pixels before encoding, see AutoOrient
+	AutoOrient bool
+	// Strip removes profiles and comments (exif data) from the output
+	Strip bool
+	// Depth is the bit depth per channel: 1, 2, 4, 8 or 16
+	Depth int
+}
+
+var pngFilters = map[string]string{
+	"none":     "0",
+	"sub":      "1",
+	"up":       "2",
+	"average":  "3",
+	"paeth":    "4",
+	"adaptive": "5",
+}
+
+// validate checks that every option set is valid for the format given by extension
+func (options *EncodeOptions) validate(extension string) (err error) {
+	format := strings.ToLower(extension)
+	is_jpeg := format == "jpg" || format == "jpeg"
+	is_png := strings.HasPrefix(format, "png")
+	is_webp := format == "webp"
+	if options.Quality < 0 || options.Quality > 100 {
+		return &MagickError{"error", "", "quality must be between 1 and 100, or 0 for the default"}
+	}
+	if options.Interlace != "" {
+		c_interlace := C.CString(options.Interlace)
+		defer C.free(unsafe.Pointer(c_interlace))
+		if C.ParseCommandOption(C.MagickInterlaceOptions, C.MagickFalse, c_interlace) < 0 {
+			return &MagickError{"error", "", "unrecognized interlace " + options.Interlace}
+		}
+	}
+	if options.SamplingFactor != "" && !is_jpeg {
+		return &MagickError{"error", "", "sampling factor is not supported for " + extension}
+	}
+	if (options.PNGCompressionLevel != 0 || options.PNGFilter != "") && !is_png {
+		return &MagickError{"error", "", "png options are not supported for " + extension}
+	}
+	if options.PNGCompressionLevel < 0 || options.PNGCompressionLevel > 9 {
+		return &MagickError{"error", "", "png compression level must be between 1 and 9, or 0 for the default"}
+	}
+	if _, ok := pngFilters[strings.ToLower(options.PNGFilter)]; options.PNGFilter != "" && !ok {
+		return &MagickError{"error", "", "unrecognized png filter " + options.PNGFilter}
+	}
+	if (options.WebPLossless || options.WebPMethod != 0) && !is_webp {
+		return &MagickError{"error", "", "webp options are not supported for " + extension}
+	}
+	if options.WebPMethod < 0 || options.WebPMethod > 6 {
+		return &MagickError{"error", "", "webp method must be between 1 and 6, or 0 for the default"}
+	}
+	switch options.Depth {
+	case 0, 1, 2, 4, 8, 16:
+	default:
+		return &MagickError{"error", "", "unsupported depth " + strconv.Itoa(options.Depth)}
+	}
+	return nil
+}
+
+// apply sets the options on im, which should be a copy of the image being encoded
+func (options *EncodeOptions) apply(im *MagickImage) (err error) {
+	if options.Quality > 0 {
+		im.Image.quality = (C.size_t)(options.Quality)
+		im.ImageInfo.quality = (C.size_t)(options.Quality)
+	}
+	if options.Interlace != "" {
+		c_interlace := C.CString(options.Interlace)
+		defer C.free(unsafe.Pointer(c_interlace))
+		interlace := C.ParseCommandOption(C.MagickInterlaceOptions, C.MagickFalse, c_interlace)
+		im.Image.interlace = (C.InterlaceType)(interlace)
+		im.ImageInfo.interlace = (C.InterlaceType)(interlace)
+	}
+	if options.SamplingFactor != "" {
+		c_factor := C.CString(options.SamplingFactor)
+		defer C.free(unsafe.Pointer(c_factor))
+		C.SetImageInfoSamplingFactor(im.ImageInfo, c_factor)
+		setImageOption(im.ImageInfo, "jpeg:sampling-factor", options.SamplingFactor)
+	}
+	if options.PNGCompressionLevel > 0 {
+		setImageOption(im.ImageInfo, "png:compression-level", strconv.Itoa(options.PNGCompressionLevel))
+	}
+	if options.PNGFilter != "" {
+		setImageOption(im.ImageInfo, "png:compression-filter", pngFilters[strings.ToLower(options.PNGFilter)])
+	}
+	if options.WebPLossless {
+		setImageOption(im.ImageInfo, "webp:lossless", "true")
+	}
+	if options.WebPMethod > 0 {
+		setImageOption(im.ImageInfo, "webp:method", strconv.Itoa(options.WebPMethod))
+	}
+	if options.AutoOrient {
+		if err = im.AutoOrient(); err != nil {
+			return err
+		}
+	}
+	if options.Strip {
+		if err = im.Strip(); err != nil {
+			return err
+		}
+	}
+	if options.Depth > 0 {
+		C.SetImageDepth(im.Image, (C.size_t)(options.Depth))
+		im.ImageInfo.depth = (C.size_t)(options.Depth)
+	}
+	return nil
+}
+
+func setImageOption(info *C.ImageInfo, option, value string) {
+	c_option := C.CString(option)
+	defer C.free(unsafe.Pointer(c_option))
+	c_value := C.CString(value)
+	defer C.free(unsafe.Pointer(c_value))
+	C.SetImageOption(info, c_option, c_value)
+}
+
+// RenditionSpec describes one output of Renditions: the image resized to Geometry
+// and encoded as Format (e.g. "jpg", "webp") with the optional Options
+type RenditionSpec struct {
+	// Name is the key of the rendition in the results, it defaults to Geometry.Format
+	Name     string
+	Geometry string
+	Format   string
+	Options  *EncodeOptions
+}
+
+// Rendition is the result of a single RenditionSpec. Err is set if the rendition
+// could not be resized or encoded.
+type Rendition struct {
+	Blob          []byte
+	Width, Height int
+	Err           error
 }
 
 type MagickError struct {
@@ -182,6 +908,17 @@ func (err *MagickError) Error() string {
 	return "MagickError " + err.Severity + ": " + err.Reason + "- " + err.Description
 }
 
+// A WriteError is returned when an image was encoded but the encoded data
+// could not be fully written to the destination io.Writer
+type WriteError struct {
+	Written int64
+	Err     error
+}
+
+func (err *WriteError) Error() string {
+	return "WriteError: wrote " + strconv.FormatInt(err.Written, 10) + " bytes: " + err.Err.Error()
+}
+
 func ErrorFromExceptionInfo(exception *C.ExceptionInfo) (err error) {
 	return &MagickError{string(exception.severity), C.GoString(exception.reason), C.GoString(exception.description)}
 }
@@ -189,13 +926,26 @@ func ErrorFromExceptionInfo(exception *C.ExceptionInfo) (err error) {
 // NewFromFile loads a file at filename into a MagickImage.
 // Exceptions are returned as MagickErrors.
 func NewFromFile(filename string) (im *MagickImage, err error) {
+	return NewFromFileWithOptions(filename, nil)
+}
+
+// NewFromFileWithOptions is like NewFromFile but decodes the file according to options,
+// e.g. to load a single page of a PDF at a given density.
+func NewFromFileWithOptions(filename string, options *LoadOptions) (im *MagickImage, err error) {
 	exception := C.AcquireExceptionInfo()
 	defer C.DestroyExceptionInfo(exception)
 	info := C.AcquireImageInfo()
 	c_filename := C.CString(filename)
 	defer C.free(unsafe.Pointer(c_filename))
 	C.SetImageInfoFilename(info, c_filename)
-	image := C.ReadImage(info, exception)
+	// load options only apply to this read, not to later writes with info
+	read_info := C.CloneImageInfo(info)
+	defer C.DestroyImageInfo(read_info)
+	if err = options.apply(read_info); err != nil {
+		C.DestroyImageInfo(info)
+		return nil, err
+	}
+	image := C.ReadImage(read_info, exception)
 	if failed := C.CheckException(exception); failed == C.MagickTrue {
 		C.DestroyImageInfo(info)
 		return nil, ErrorFromExceptionInfo(exception)
@@ -207,6 +957,12 @@ func NewFromFile(filename string) (im *MagickImage, err error) {
 // image type (e.g. "png", "jpg", etc). It loads the image data and returns a MagickImage.
 // The extension is required so that Magick knows what processor to use.
 func NewFromBlob(blob []byte, extension string) (im *MagickImage, err error) {
+	return NewFromBlobWithOptions(blob, extension, nil)
+}
+
+// NewFromBlobWithOptions is like NewFromBlob but decodes the image data according to options,
+// e.g. to load a single page of a PDF at a given density.
+func NewFromBlobWithOptions(blob []byte, extension string, options *LoadOptions) (im *MagickImage, err error) {
 	if len(blob) < 1 {
 		return nil, &MagickError{"fatal", "", "zero length blob passed to NewFromBlob"}
 	}
@@ -225,16 +981,29 @@ func NewFromBlob(blob []byte, extension string) (im *MagickImage, err error) {
 	if success != C.MagickTrue {
 		return nil, ErrorFromExceptionInfo(exception)
 	}
+	// load options only apply to this read, not to later writes with the returned info
 	cloned_info := C.CloneImageInfo(info)
+	if err = options.apply(info); err != nil {
+		C.DestroyImageInfo(cloned_info)
+		return nil, err
+	}
 	success = C.GetBlobSupport(info)
 	if success != C.MagickTrue {
 		// No blob support, lets try reading from a file
+		C.DestroyImageInfo(cloned_info)
 		file, err := ioutil.TempFile("", "image."+extension)
-		if _, err = file.Write(blob); err != nil {
+		if err != nil {
+			return nil, &MagickError{"fatal", "", "image format " + extension + " does not support blobs and could not create temp file"}
+		}
+		defer os.Remove(file.Name())
+		_, err = file.Write(blob)
+		if close_err := file.Close(); err == nil {
+			err = close_err
+		}
+		if err != nil {
 			return nil, &MagickError{"fatal", "", "image format " + extension + " does not support blobs and could not write temp file"}
 		}
-		file.Close()
-		return NewFromFile(file.Name())
+		return NewFromFileWithOptions(file.Name(), options)
 	}
 	length := (C.size_t)(len(blob))
 	if length == 0 {
@@ -254,32 +1023,339 @@ func NewFromBlob(blob []byte, extension string) (im *MagickImage, err error) {
 	return &MagickImage{Image: image, ImageInfo: cloned_info}, nil
 }
 
-// Destroy frees the C memory for the image. Should be called after processing is done.
-func (im *MagickImage) Destroy() (err error) {
-	if im.Image != nil {
-		C.DestroyImage(im.Image)
+// DetectFormat sniffs the magic bytes at the start of blob and returns the image format
+// as Magick names it (e.g. "PNG", "JPEG") along with its MIME type. It does not trust or
+// need a file extension, so it can be used to validate client supplied image data.
+func DetectFormat(blob []byte) (format, mimeType string, err error) {
+	if len(blob) < 1 {
+		return "", "", &MagickError{"fatal", "", "zero length blob passed to DetectFormat"}
 	}
-	if im.ImageInfo != nil {
-		C.DestroyImageInfo(im.ImageInfo)
+	exception := C.AcquireExceptionInfo()
+	defer C.DestroyExceptionInfo(exception)
+	info := C.AcquireImageInfo()
+	defer C.DestroyImageInfo(info)
+	blob_start := unsafe.Pointer(&blob[0])
+	success := C.DetectBlobFormat(info, blob_start, (C.size_t)(len(blob)), exception)
+	if success != C.MagickTrue {
+		return "", "", ErrorFromExceptionInfo(exception)
 	}
-	im.Image = nil
-	im.ImageInfo = nil
-	return
-}
-
-// ReplaceImage Replaces the underlying image, freeing the old one
-func (im *MagickImage) ReplaceImage(new_image *C.Image) {
-	C.DestroyImage(im.Image)
-	im.Image = new_image
+	format = C.GoString(&info.magick[0])
+	if format == "" {
+		return "", "", &MagickError{"error", "", "could not detect image format"}
+	}
+	c_format := C.CString(format)
+	defer C.free(unsafe.Pointer(c_format))
+	c_mime := C.MagickToMime(c_format)
+	if c_mime != nil {
+		defer C.free(unsafe.Pointer(c_mime))
+		mimeType = C.GoString(c_mime)
+	}
+	return format, mimeType, nil
 }
 
-// Width returns the Width of the loaded image in pixels as an int
-func (im *MagickImage) Width() int {
-	return (int)(im.Image.columns)
+// NewFromBlobAuto works like NewFromBlob but detects the image type from the data itself
+// with DetectFormat instead of requiring an extension.
+func NewFromBlobAuto(blob []byte) (im *MagickImage, err error) {
+	format, _, err := DetectFormat(blob)
+	if err != nil {
+		return nil, err
+	}
+	return NewFromBlob(blob, strings.ToLower(format))
 }
 
-// Height returns the Height of the loaded image in pixels as an int
-func (im *MagickImage) Height() int {
+// NewFromReader reads image data from r and returns a MagickImage. Like NewFromBlob
+// the extension (e.g. "png", "jpg") tells Magick which processor to use. The data is
+// streamed into MagickCore rather than buffered into a byte slice first, unless the
+// format does not support blobs in which case it is copied to a temp file.
+func NewFromReader(r io.Reader, extension string) (im *MagickImage, err error) {
+	if r == nil {
+		return nil, &MagickError{"fatal", "", "nil reader passed to NewFromReader"}
+	}
+	if len(extension) < 1 {
+		return nil, &MagickError{"fatal", "", "zero length extension passed to NewFromReader"}
+	}
+	exception := C.AcquireExceptionInfo()
+	defer C.DestroyExceptionInfo(exception)
+	info := C.AcquireImageInfo()
+	c_filename := C.CString("image." + extension)
+	defer C.free(unsafe.Pointer(c_filename))
+	C.SetImageInfoFilename(info, c_filename)
+	if success := C.SetImageInfo(info, 1, exception); success != C.MagickTrue {
+		C.DestroyImageInfo(info)
+		return nil, ErrorFromExceptionInfo(exception)
+	}
+	if success := C.GetBlobSupport(info); success != C.MagickTrue {
+		// No blob support, lets try reading from a file
+		C.DestroyImageInfo(info)
+		file, err := ioutil.TempFile("", "image."+extension)
+		if err != nil {
+			return nil, &MagickError{"fatal", "", "image format " + extension + " does not support blobs and could not create temp file"}
+		}
+		defer os.Remove(file.Name())
+		_, err = io.Copy(file, r)
+		if close_err := file.Close(); err == nil {
+			err = close_err
+		}
+		if err != nil {
+			return nil, &MagickError{"fatal", "", "image format " + extension + " does not support blobs and could not write temp file"}
+		}
+		return NewFromFile(file.Name())
+	}
+	source := newStream(r)
+	handle := cgo.NewHandle(source)
+	defer handle.Delete()
+	var seekable C.int
+	if source.seeker != nil {
+		seekable = 1
+	}
+	image := C.ReadImageFromStream(info, (C.uintptr_t)(handle), seekable, exception)
+	if source.err != nil {
+		if image != nil {
+			C.DestroyImageList(image)
+		}
+		C.DestroyImageInfo(info)
+		return nil, &MagickError{"fatal", "", "could not read from reader: " + source.err.Error()}
+	}
+	if image == nil {
+		C.DestroyImageInfo(info)
+		return nil, &MagickError{"fatal", "", "corrupt image, not a " + extension}
+	}
+	if failed := C.CheckException(exception); failed == C.MagickTrue {
+		C.DestroyImageList(image)
+		C.DestroyImageInfo(info)
+		return nil, ErrorFromExceptionInfo(exception)
+	}
+	return &MagickImage{Image: image, ImageInfo: info}, nil
+}
+
+// Ping reads the attributes of the image at filename (dimensions, format, colorspace,
+// frame count and orientation) without decoding the pixel data. This is much faster
+// and uses far less memory than NewFromFile when only the metadata is needed.
+func Ping(filename string) (info *MagickPingInfo, err error) {
+	exception := C.AcquireExceptionInfo()
+	defer C.DestroyExceptionInfo(exception)
+	image_info := C.AcquireImageInfo()
+	defer C.DestroyImageInfo(image_info)
+	c_filename := C.CString(filename)
+	defer C.free(unsafe.Pointer(c_filename))
+	C.SetImageInfoFilename(image_info, c_filename)
+	image := C.PingImage(image_info, exception)
+	if failed := C.CheckException(exception); failed == C.MagickTrue {
+		if image != nil {
+			C.DestroyImageList(image)
+		}
+		return nil, ErrorFromExceptionInfo(exception)
+	}
+	if image == nil {
+		return nil, &MagickError{"fatal", "", "could not ping " + filename}
+	}
+	defer C.DestroyImageList(image)
+	return pingInfoFromImage(image), nil
+}
+
+// PingBlob is like Ping but reads the attributes from a byte slice of image data. As with
+// NewFromBlob the extension (e.g. "png", "jpg") tells Magick which processor to use.
+func PingBlob(blob []byte, extension string) (info *MagickPingInfo, err error) {
+	if len(blob) < 1 {
+		return nil, &MagickError{"fatal", "", "zero length blob passed to PingBlob"}
+	}
+	if len(extension) < 1 {
+		return nil, &MagickError{"fatal", "", "zero length extension passed to PingBlob"}
+	}
+	exception := C.AcquireExceptionInfo()
+	defer C.DestroyExceptionInfo(exception)
+	image_info := C.AcquireImageInfo()
+	defer C.DestroyImageInfo(image_info)
+	c_filename := C.CString("image." + extension)
+	defer C.free(unsafe.Pointer(c_filename))
+	C.SetImageInfoFilename(image_info, c_filename)
+	if success := C.SetImageInfo(image_info, 1, exception); success != C.MagickTrue {
+		return nil, ErrorFromExceptionInfo(exception)
+	}
+	if success := C.GetBlobSupport(image_info); success != C.MagickTrue {
+		// No blob support, lets try pinging a file
+		file, err := ioutil.TempFile("", "image."+extension)
+		if err != nil {
+			return nil, &MagickError{"fatal", "", "image format " + extension + " does not support blobs and could not create temp file"}
+		}
+		defer os.Remove(file.Name())
+		_, err = file.Write(blob)
+		if close_err := file.Close(); err == nil {
+			err = close_err
+		}
+		if err != nil {
+			return nil, &MagickError{"fatal", "", "image format " + extension + " does not support blobs and could not write temp file"}
+		}
+		return Ping(file.Name())
+	}
+	blob_start := unsafe.Pointer(&blob[0])
+	image := C.PingBlob(image_info, blob_start, (C.size_t)(len(blob)), exception)
+	if failed := C.CheckException(exception); failed == C.MagickTrue {
+		if image != nil {
+			C.DestroyImageList(image)
+		}
+		return nil, ErrorFromExceptionInfo(exception)
+	}
+	if image == nil {
+		return nil, &MagickError{"fatal", "", "corrupt image, not a " + extension}
+	}
+	defer C.DestroyImageList(image)
+	return pingInfoFromImage(image), nil
+}
+
+// PageCount returns the number of pages or frames in the image data without rasterizing them
+func PageCount(blob []byte, extension string) (count int, err error) {
+	info, err := PingBlob(blob, extension)
+	if err != nil {
+		return 0, err
+	}
+	return info.Frames, nil
+}
+
+func pingInfoFromImage(image *C.Image) *MagickPingInfo {
+	return &MagickPingInfo{
+		Width:       int(image.columns),
+		Height:      int(image.rows),
+		Format:      C.GoString(&image.magick[0]),
+		Colorspace:  C.GoString(C.CommandOptionToMnemonic(C.MagickColorspaceOptions, (C.ssize_t)(image.colorspace))),
+		Frames:      int(C.GetImageListLength(image)),
+		Orientation: C.GoString(C.CommandOptionToMnemonic(C.MagickOrientationOptions, (C.ssize_t)(image.orientation))),
+	}
+}
+
+// NewFromPixels creates a width x height MagickImage from raw pixel data such as frames from
+// a video decoder. channels describes the order of the channels in data, e.g. "RGB", "RGBA",
+// "BGR" or "I" for grayscale, and storage the size and type of each channel value.
+func NewFromPixels(width, height int, channels string, storage StorageType, data []byte) (im *MagickImage, err error) {
+	if width < 1 || height < 1 {
+		return nil, &MagickError{"fatal", "", "invalid image size " + strconv.Itoa(width) + "x" + strconv.Itoa(height)}
+	}
+	if len(channels) < 1 {
+		return nil, &MagickError{"fatal", "", "zero length channel map passed to NewFromPixels"}
+	}
+	if len(data) != width*height*len(channels)*storage.size() {
+		return nil, &MagickError{"fatal", "", "pixel data does not match " + strconv.Itoa(width) + "x" + strconv.Itoa(height) + " " + channels}
+	}
+	exception := C.AcquireExceptionInfo()
+	defer C.DestroyExceptionInfo(exception)
+	c_map := C.CString(channels)
+	defer C.free(unsafe.Pointer(c_map))
+	new_image := C.ConstituteImage((C.size_t)(width), (C.size_t)(height), c_map, storage.storageType(), unsafe.Pointer(&data[0]), exception)
+	if failed := C.CheckException(exception); failed == C.MagickTrue {
+		if new_image != nil {
+			C.DestroyImage(new_image)
+		}
+		return nil, ErrorFromExceptionInfo(exception)
+	}
+	if new_image == nil {
+		return nil, &MagickError{"fatal", "", "could not create image from pixels"}
+	}
+	return &MagickImage{Image: new_image, ImageInfo: C.AcquireImageInfo()}, nil
+}
+
+// NewFromGoImage creates a MagickImage from an image.Image from Go's image package by
+// handing its pixels directly to MagickCore, avoiding an encode/decode round-trip.
+// 16-bit images keep their full depth, everything else is imported as 8-bit RGBA.
+func NewFromGoImage(img image.Image) (im *MagickImage, err error) {
+	bounds := img.Bounds()
+	if bounds.Empty() {
+		return nil, &MagickError{"fatal", "", "empty image passed to NewFromGoImage"}
+	}
+	switch img.(type) {
+	case *image.NRGBA64, *image.RGBA64, *image.Gray16:
+		nrgba := image.NewNRGBA64(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+		draw.Draw(nrgba, nrgba.Bounds(), img, bounds.Min, draw.Src)
+		// Go stores 16-bit samples big endian, MagickCore expects native shorts
+		data := make([]byte, len(nrgba.Pix))
+		shorts := unsafe.Slice((*uint16)(unsafe.Pointer(&data[0])), len(data)/2)
+		for i := range shorts {
+			shorts[i] = uint16(nrgba.Pix[2*i])<<8 | uint16(nrgba.Pix[2*i+1])
+		}
+		return NewFromPixels(bounds.Dx(), bounds.Dy(), "RGBA", ShortPixel, data)
+	}
+	nrgba, ok := img.(*image.NRGBA)
+	if !ok || bounds.Min != (image.Point{}) || nrgba.Stride != 4*bounds.Dx() {
+		nrgba = image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+		draw.Draw(nrgba, nrgba.Bounds(), img, bounds.Min, draw.Src)
+	}
+	// a SubImage shares the Pix of its parent, which can extend past its last row
+	return NewFromPixels(bounds.Dx(), bounds.Dy(), "RGBA", CharPixel, nrgba.Pix[:4*bounds.Dx()*bounds.Dy()])
+}
+
+// NewCanvas creates a new width x height image filled with a solid color. color can be any
+// color format that image magick understands, see: http://www.imagemagick.org/script/color.php
+func NewCanvas(width, height int, color string) (im *MagickImage, err error) {
+	return newFromPseudoImage("xc:"+color, width, height)
+}
+
+// NewLinearGradient creates a new width x height image with a linear gradient running
+// from the color from at the top to the color to at the bottom.
+func NewLinearGradient(width, height int, from, to string) (im *MagickImage, err error) {
+	return newFromPseudoImage("gradient:"+from+"-"+to, width, height)
+}
+
+// NewRadialGradient creates a new width x height image with a radial gradient running
+// from the color from at the center to the color to at the edges.
+func NewRadialGradient(width, height int, from, to string) (im *MagickImage, err error) {
+	return newFromPseudoImage("radial-gradient:"+from+"-"+to, width, height)
+}
+
+// newFromPseudoImage reads one of Magick's built in pseudo image formats (e.g. "xc:red")
+// at the given size
+func newFromPseudoImage(filename string, width, height int) (im *MagickImage, err error) {
+	if width < 1 || height < 1 {
+		return nil, &MagickError{"fatal", "", "invalid canvas size " + strconv.Itoa(width) + "x" + strconv.Itoa(height)}
+	}
+	exception := C.AcquireExceptionInfo()
+	defer C.DestroyExceptionInfo(exception)
+	info := C.AcquireImageInfo()
+	c_size := C.CString(strconv.Itoa(width) + "x" + strconv.Itoa(height))
+	defer C.free(unsafe.Pointer(c_size))
+	C.SetImageInfoSize(info, c_size)
+	c_filename := C.CString(filename)
+	defer C.free(unsafe.Pointer(c_filename))
+	C.SetImageInfoFilename(info, c_filename)
+	image := C.ReadImage(info, exception)
+	if failed := C.CheckException(exception); failed == C.MagickTrue {
+		if image != nil {
+			C.DestroyImageList(image)
+		}
+		C.DestroyImageInfo(info)
+		return nil, ErrorFromExceptionInfo(exception)
+	}
+	if image == nil {
+		C.DestroyImageInfo(info)
+		return nil, &MagickError{"fatal", "", "could not create " + filename}
+	}
+	return &MagickImage{Image: image, ImageInfo: info}, nil
+}
+
+// Destroy frees the C memory for the image. Should be called after processing is done.
+func (im *MagickImage) Destroy() (err error) {
+	if im.Image != nil {
+		C.DestroyImage(im.Image)
+	}
+	if im.ImageInfo != nil {
+		C.DestroyImageInfo(im.ImageInfo)
+	}
+	im.Image = nil
+	im.ImageInfo = nil
+	return
+}
+
+// ReplaceImage Replaces the underlying image, freeing the old one
+func (im *MagickImage) ReplaceImage(new_image *C.Image) {
+	C.DestroyImage(im.Image)
+	im.Image = new_image
+}
+
+// Width returns the Width of the loaded image in pixels as an int
+func (im *MagickImage) Width() int {
+	return (int)(im.Image.columns)
+}
+
+// Height returns the Height of the loaded image in pixels as an int
+func (im *MagickImage) Height() int {
 	return (int)(im.Image.rows)
 }
 
@@ -288,6 +1364,59 @@ func (im *MagickImage) Type() (t string) {
 	return strings.Trim(string(C.GoBytes(unsafe.Pointer(&im.Image.magick), 4096)), "\x00")
 }
 
+// ExportPixels returns the raw pixel data of the width x height region at x, y. channels
+// sets the order of the channels in the result, e.g. "RGB", "RGBA", "BGR" or "I" for
+// grayscale, and storage the size and type of each channel value. The region must lie
+// within the image.
+func (im *MagickImage) ExportPixels(x, y, width, height int, channels string, storage StorageType) (data []byte, err error) {
+	if width < 1 || height < 1 {
+		return nil, &MagickError{"fatal", "", "invalid region size " + strconv.Itoa(width) + "x" + strconv.Itoa(height)}
+	}
+	if x < 0 || y < 0 || x+width > im.Width() || y+height > im.Height() {
+		return nil, &MagickError{"error", "", "region " + strconv.Itoa(width) + "x" + strconv.Itoa(height) + "+" +
+			strconv.Itoa(x) + "+" + strconv.Itoa(y) + " is outside the image"}
+	}
+	if len(channels) < 1 {
+		return nil, &MagickError{"fatal", "", "zero length channel map passed to ExportPixels"}
+	}
+	exception := C.AcquireExceptionInfo()
+	defer C.DestroyExceptionInfo(exception)
+	c_map := C.CString(channels)
+	defer C.free(unsafe.Pointer(c_map))
+	data = make([]byte, width*height*len(channels)*storage.size())
+	C.ExportImagePixels(im.Image, (C.ssize_t)(x), (C.ssize_t)(y), (C.size_t)(width), (C.size_t)(height), c_map, storage.storageType(), unsafe.Pointer(&data[0]), exception)
+	if failed := C.CheckException(exception); failed == C.MagickTrue {
+		return nil, ErrorFromExceptionInfo(exception)
+	}
+	return data, nil
+}
+
+// ToGoImage exports the pixels of the image into an image.Image from Go's image package
+// without encoding. Images with a depth of 8 bits or less are returned as an *image.NRGBA,
+// deeper images as an *image.NRGBA64 so no precision is lost. Both are non-premultiplied
+// like Magick's own pixels, so alpha is kept exactly rather than as an *image.RGBA64.
+func (im *MagickImage) ToGoImage() (img image.Image, err error) {
+	rect := image.Rect(0, 0, im.Width(), im.Height())
+	if im.Image.depth > 8 {
+		data, err := im.ExportPixels(0, 0, im.Width(), im.Height(), "RGBA", ShortPixel)
+		if err != nil {
+			return nil, err
+		}
+		nrgba := image.NewNRGBA64(rect)
+		shorts := unsafe.Slice((*uint16)(unsafe.Pointer(&data[0])), len(data)/2)
+		for i, value := range shorts {
+			nrgba.Pix[2*i] = uint8(value >> 8)
+			nrgba.Pix[2*i+1] = uint8(value)
+		}
+		return nrgba, nil
+	}
+	data, err := im.ExportPixels(0, 0, im.Width(), im.Height(), "RGBA", CharPixel)
+	if err != nil {
+		return nil, err
+	}
+	return &image.NRGBA{Pix: data, Stride: 4 * im.Width(), Rect: rect}, nil
+}
+
 // ResizeRatio() returns the ratio that the size you want to resize to
 // defined by width/height is over the size of the underlying Image
 func (im *MagickImage) ResizeRatio(width, height int) float64 {
@@ -318,27 +1447,72 @@ func (im *MagickImage) SetProperty(prop, value string) (err error) {
 	return
 }
 
+// Delay returns how long the image is shown as a frame of an animation, in hundredths of a second
+func (im *MagickImage) Delay() int {
+	ticks := int(im.Image.ticks_per_second)
+	if ticks <= 0 {
+		ticks = 100
+	}
+	return int(im.Image.delay) * 100 / ticks
+}
+
+// Iterations returns how many times an animation the image belongs to is played, 0 means forever
+func (im *MagickImage) Iterations() int {
+	return int(im.Image.iterations)
+}
+
+// Dispose returns what happens to the image as a frame of an animation before the next frame is shown
+func (im *MagickImage) Dispose() DisposeType {
+	return DisposeType(im.Image.dispose)
+}
+
 // ParseGeometryToRectangleInfo converts from a geometry string (WxH+X+Y) into a Magick
 // RectangleInfo that contains the individual properties
 func (im *MagickImage) ParseGeometryToRectangleInfo(geometry string) (info C.RectangleInfo, err error) {
+	info, _, err = im.parseRegionGeometry(geometry)
+	return
+}
+
+// parseRegionGeometry is ParseGeometryToRectangleInfo that also returns the flags found in geometry
+func (im *MagickImage) parseRegionGeometry(geometry string) (info C.RectangleInfo, flags C.MagickStatusType, err error) {
 	c_geometry := C.CString(geometry)
 	defer C.free(unsafe.Pointer(c_geometry))
 	exception := C.AcquireExceptionInfo()
 	defer C.DestroyExceptionInfo(exception)
-	C.ParseRegionGeometry(im.Image, c_geometry, &info, exception)
+	flags = C.ParseRegionGeometry(im.Image, c_geometry, &info, exception)
 	if failed := C.CheckException(exception); failed == C.MagickTrue {
 		err = ErrorFromExceptionInfo(exception)
 	}
 	return
 }
 
-// ParseGeometry uses ParseGeometryToRectangleInfo to convert from a geometry string into a MagickGeometry
+// ParseGeometry uses ParseGeometryToRectangleInfo to convert from a geometry string into a MagickGeometry,
+// applying all of the geometry flags (^, >, <, %, @ and !) against the current size of the image
 func (im *MagickImage) ParseGeometry(geometry string) (info *MagickGeometry, err error) {
-	rectangle, err := im.ParseGeometryToRectangleInfo(geometry)
+	rectangle, flags, err := im.parseRegionGeometry(geometry)
 	if err != nil {
 		return nil, err
 	}
-	return &MagickGeometry{int(rectangle.width), int(rectangle.height), int(rectangle.x), int(rectangle.y)}, nil
+	return &MagickGeometry{
+		Width:   int(rectangle.width),
+		Height:  int(rectangle.height),
+		Xoffset: int(rectangle.x),
+		Yoffset: int(rectangle.y),
+		Flags:   geometryFlagsFromStatus(flags),
+	}, nil
+}
+
+// Clone returns a deep copy of the MagickImage, duplicating both the Image and the
+// ImageInfo, so several variants can be made from one decode. The copy must be
+// destroyed independently of the original.
+func (im *MagickImage) Clone() (duplicate *MagickImage, err error) {
+	exception := C.AcquireExceptionInfo()
+	defer C.DestroyExceptionInfo(exception)
+	new_image := C.CloneImage(im.Image, 0, 0, C.MagickTrue, exception)
+	if failed := C.CheckException(exception); failed == C.MagickTrue {
+		return nil, ErrorFromExceptionInfo(exception)
+	}
+	return &MagickImage{Image: new_image, ImageInfo: C.CloneImageInfo(im.ImageInfo)}, nil
 }
 
 // Progessive() is a shortcut for making the underlying image a
@@ -351,25 +1525,82 @@ func (im *MagickImage) Quality(quality int) {
 	im.Image.quality = (C.size_t)(quality)
 }
 
-// Resize resizes the image based on the geometry string passed and stores the resized image in place
+// Resize resizes the image based on the geometry string passed and stores the resized image in place.
+// All geometry flags are honoured: by default the image is fit inside WxH keeping its aspect ratio,
+// ^ fills WxH instead, > only shrinks, < only enlarges, % scales, @ limits the pixel area and ! forces
+// the exact size. If the geometry leaves the size unchanged the image is left untouched.
 // For more info about Geometry see http://www.imagemagick.org/script/command-line-processing.php#geometry
 func (im *MagickImage) Resize(geometry string) (err error) {
+	return im.ResizeWithOptions(geometry, nil)
+}
+
+// ResizeWithOptions is like Resize but lets you pick the resize method, filter and blur
+// with options. A nil options uses the same heuristic as Resize.
+func (im *MagickImage) ResizeWithOptions(geometry string, options *ResizeOptions) (err error) {
+	if options == nil {
+		options = &ResizeOptions{}
+	}
 	exception := C.AcquireExceptionInfo()
 	defer C.DestroyExceptionInfo(exception)
 	rect, err := im.ParseGeometryToRectangleInfo(geometry)
 	if err != nil {
 		return err
 	}
-	ratio := im.ResizeRatio(int(rect.width), int(rect.height))
+	if int(rect.width) == im.Width() && int(rect.height) == im.Height() {
+		return nil
+	}
+	filter := im.Image.filter
+	if options.Filter != "" {
+		c_filter := C.CString(options.Filter)
+		defer C.free(unsafe.Pointer(c_filter))
+		parsed := C.ParseCommandOption(C.MagickFilterOptions, C.MagickFalse, c_filter)
+		if parsed < 0 {
+			return &MagickError{"error", "", "unrecognized filter " + options.Filter}
+		}
+		filter = (C.FilterTypes)(parsed)
+	}
+	blur := im.Image.blur
+	if options.Blur < 0 {
+		return &MagickError{"error", "", "blur can not be negative"}
+	}
+	if options.Blur > 0 {
+		blur = (C.double)(options.Blur)
+	}
+	method := options.Method
+	if method == DefaultResize {
+		ratio := im.ResizeRatio(int(rect.width), int(rect.height))
+		if ratio > 0.4 {
+			method = AdaptiveResize
+		} else {
+			method = ThumbnailResize
+		}
+	}
 	var new_image *C.Image
-	if ratio > 0.4 {
+	switch method {
+	case FilterResize:
+		new_image = C.ResizeImage(im.Image, rect.width, rect.height, filter, blur, exception)
+	case ThumbnailResize:
+		new_image = C.ThumbnailImageWithFilter(im.Image, rect.width, rect.height, filter, blur, exception)
+	case SampleResize:
+		new_image = C.SampleImage(im.Image, rect.width, rect.height, exception)
+	case ScaleResize:
+		new_image = C.ScaleImage(im.Image, rect.width, rect.height, exception)
+	case AdaptiveResize:
 		new_image = C.AdaptiveResizeImage(im.Image, rect.width, rect.height, exception)
-	} else {
-		new_image = C.ThumbnailImage(im.Image, rect.width, rect.height, exception)
+	case LiquidResize:
+		new_image = C.LiquidRescaleImage(im.Image, rect.width, rect.height, 1.0, 0.0, exception)
+	default:
+		return &MagickError{"error", "", "unknown resize method " + strconv.Itoa(int(method))}
 	}
 	if failed := C.CheckException(exception); failed == C.MagickTrue {
+		if new_image != nil {
+			C.DestroyImage(new_image)
+		}
 		return ErrorFromExceptionInfo(exception)
 	}
+	if new_image == nil {
+		return &MagickError{"error", "", "could not resize image"}
+	}
 	im.ReplaceImage(new_image)
 	return nil
 }
@@ -391,76 +1622,707 @@ func (im *MagickImage) Crop(geometry string) (err error) {
 	return nil
 }
 
-// Shadow adds a dropshadow to the current (transparent) image and stores the shadowed image in place
-// For more information about shadow options see: http://www.imagemagick.org/Usage/blur/#shadow
-func (im *MagickImage) Shadow(color string, opacity, sigma float32, xoffset, yoffset int) (err error) {
+// CropWithGravity crops the image based on the geometry string passed, positioning the region
+// relative to gravity instead of the top left corner, e.g. "200x200" with CenterGravity crops the
+// center of the image. The cropped image is stored in place with its virtual canvas reset.
+// For more info about Geometry see http://www.imagemagick.org/script/command-line-processing.php#geometry
+func (im *MagickImage) CropWithGravity(geometry string, gravity Gravity) (err error) {
 	exception := C.AcquireExceptionInfo()
 	defer C.DestroyExceptionInfo(exception)
-	c_opacity := (C.double)(opacity)
-	c_sigma := (C.double)(sigma)
-	c_x := (C.ssize_t)(xoffset)
-	c_y := (C.ssize_t)(yoffset)
-	c_color := C.CString(color)
-	defer C.free(unsafe.Pointer(c_color))
-	new_image := C.AddShadowToImage(im.Image, c_color, c_opacity, c_sigma, c_x, c_y, exception)
+	c_geometry := C.CString(geometry)
+	defer C.free(unsafe.Pointer(c_geometry))
+	var rect C.RectangleInfo
+	C.ParseGravityRegion(im.Image, c_geometry, (C.GravityType)(gravity), &rect, exception)
 	if failed := C.CheckException(exception); failed == C.MagickTrue {
 		return ErrorFromExceptionInfo(exception)
 	}
-	im.ReplaceImage(new_image)
-	return nil
-}
-
-// FillBackgroundColor fills transparent areas of an image with a solid color and stores the filled image in place.
-// color can be any color format that image magick understands, see: http://www.imagemagick.org/ImageMagick-7.0.0/script/color.php#models
-func (im *MagickImage) FillBackgroundColor(color string) (err error) {
-	exception := C.AcquireExceptionInfo()
-	defer C.DestroyExceptionInfo(exception)
-	c_color := C.CString(color)
-	defer C.free(unsafe.Pointer(c_color))
-	new_image := C.FillBackgroundColor(im.Image, c_color, exception)
+	new_image := C.CropImage(im.Image, &rect, exception)
 	if failed := C.CheckException(exception); failed == C.MagickTrue {
+		if new_image != nil {
+			C.DestroyImage(new_image)
+		}
 		return ErrorFromExceptionInfo(exception)
 	}
+	C.ResetImageListPage(new_image)
 	im.ReplaceImage(new_image)
 	return nil
 }
 
-// SeparateAlphaChannel replaces the Image with grayscale data from the image's Alpha Channel values
-func (im *MagickImage) SeparateAlphaChannel() (err error) {
+// Thumbnail resizes the image to a width x height thumbnail in place. mode decides what happens
+// when the aspect ratio of the image differs: FitThumbnail keeps the whole image inside the box,
+// FillThumbnail fills the box and center crops the rest and PadThumbnail pads the image to the box.
+func (im *MagickImage) Thumbnail(width, height int, mode ThumbnailMode) (err error) {
+	if width < 1 || height < 1 {
+		return &MagickError{"error", "", "invalid thumbnail size " + strconv.Itoa(width) + "x" + strconv.Itoa(height)}
+	}
+	size := strconv.Itoa(width) + "x" + strconv.Itoa(height)
+	switch mode {
+	case FitThumbnail:
+		return im.Resize(size)
+	case FillThumbnail:
+		if err = im.Resize(size + "^"); err != nil {
+			return err
+		}
+		return im.CropWithGravity(size, CenterGravity)
+	case PadThumbnail:
+		if err = im.Resize(size); err != nil {
+			return err
+		}
+		return im.extent(width, height, 0, 0, CenterGravity, "none")
+	}
+	return &MagickError{"error", "", "unknown thumbnail mode " + strconv.Itoa(int(mode))}
+}
+
+// Extent changes the canvas of the image to the size given by geometry and stores the result
+// in place. The image is placed on the new canvas according to gravity (and any offsets in
+// geometry), so it can both pad and crop. New areas are filled with color, which can be "none"
+// to keep them transparent. color can be any color format that image magick understands,
+// see: http://www.imagemagick.org/script/color.php
+func (im *MagickImage) Extent(geometry string, gravity Gravity, color string) (err error) {
 	exception := C.AcquireExceptionInfo()
 	defer C.DestroyExceptionInfo(exception)
-	new_image := C.SeparateAlphaChannel(im.Image, exception)
+	c_geometry := C.CString(geometry)
+	defer C.free(unsafe.Pointer(c_geometry))
+	var rect C.RectangleInfo
+	C.ParseGravityRegion(im.Image, c_geometry, C.NorthWestGravity, &rect, exception)
 	if failed := C.CheckException(exception); failed == C.MagickTrue {
 		return ErrorFromExceptionInfo(exception)
 	}
-	im.ReplaceImage(new_image)
-	return nil
+	if rect.width == 0 || rect.height == 0 {
+		return &MagickError{"error", "", "invalid extent " + geometry}
+	}
+	return im.extent(int(rect.width), int(rect.height), int(rect.x), int(rect.y), gravity, color)
 }
 
-// Negate inverts the colors in the image
-func (im *MagickImage) Negate() (err error) {
+// extent changes the canvas of the image to width x height, placing the image according to gravity
+// and the offsets and filling the new area with color
+func (im *MagickImage) extent(width, height, xoffset, yoffset int, gravity Gravity, color string) (err error) {
 	exception := C.AcquireExceptionInfo()
 	defer C.DestroyExceptionInfo(exception)
-	new_image := C.Negate(im.Image, exception)
+	c_color := C.CString(color)
+	defer C.free(unsafe.Pointer(c_color))
+	new_image := C.ExtentWithGravity(im.Image, (C.size_t)(width), (C.size_t)(height), (C.ssize_t)(xoffset), (C.ssize_t)(yoffset), (C.GravityType)(gravity), c_color, exception)
 	if failed := C.CheckException(exception); failed == C.MagickTrue {
+		if new_image != nil {
+			C.DestroyImage(new_image)
+		}
 		return ErrorFromExceptionInfo(exception)
 	}
+	if new_image == nil {
+		return &MagickError{"error", "", "could not extend image"}
+	}
 	im.ReplaceImage(new_image)
 	return nil
 }
 
-// Strip strips the image of its extra meta (exif) data
-func (im *MagickImage) Strip() (err error) {
-	ok := C.StripImage(im.Image)
-	if ok == C.MagickFalse {
-		return &MagickError{"error", "", "could not strip image"}
+// SmartCrop crops the image to the most interesting region with the aspect ratio of width x height
+// and resizes it to exactly width x height in place. Candidate regions are the largest that fit the
+// image, scored by the edge energy inside them. The region chosen, in the coordinates of the
+// original image, is returned so it can be stored as a focal point.
+func (im *MagickImage) SmartCrop(width, height int) (geometry *MagickGeometry, err error) {
+	if width < 1 || height < 1 {
+		return nil, &MagickError{"error", "", "invalid crop size " + strconv.Itoa(width) + "x" + strconv.Itoa(height)}
 	}
-	return
-}
-
-// ToBlob takes a (transformed) MagickImage and returns a byte slice in the format you specify with extension.
-// Magick uses the extension to transform the image in to the proper encoding (e.g. "jpg", "png")
-func (im *MagickImage) ToBlob(extension string) (blob []byte, err error) {
+	crop_width, crop_height := im.cropWindow(width, height)
+	// score a small edge detected copy of the image
+	analysis, err := im.Clone()
+	if err != nil {
+		return nil, err
+	}
+	defer analysis.Destroy()
+	if longest := math.Max(float64(im.Width()), float64(im.Height())); longest > 256 {
+		scale := 256 / longest
+		size := strconv.Itoa(int(math.Max(1, float64(im.Width())*scale))) + "x" + strconv.Itoa(int(math.Max(1, float64(im.Height())*scale))) + "!"
+		if err = analysis.ResizeWithOptions(size, &ResizeOptions{Method: ScaleResize}); err != nil {
+			return nil, err
+		}
+	}
+	exception := C.AcquireExceptionInfo()
+	defer C.DestroyExceptionInfo(exception)
+	edge_image := C.EdgeImage(analysis.Image, 1.0, exception)
+	if failed := C.CheckException(exception); failed == C.MagickTrue {
+		if edge_image != nil {
+			C.DestroyImage(edge_image)
+		}
+		return nil, ErrorFromExceptionInfo(exception)
+	}
+	analysis.ReplaceImage(edge_image)
+	energy, err := analysis.ExportPixels(0, 0, analysis.Width(), analysis.Height(), "I", CharPixel)
+	if err != nil {
+		return nil, err
+	}
+	columns, rows := analysis.Width(), analysis.Height()
+	// summed area table of the edge energy, one larger than the image on each side
+	sums := make([]int64, (columns+1)*(rows+1))
+	for y := 0; y < rows; y++ {
+		var row int64
+		for x := 0; x < columns; x++ {
+			row += int64(energy[y*columns+x])
+			sums[(y+1)*(columns+1)+x+1] = sums[y*(columns+1)+x+1] + row
+		}
+	}
+	scale_x := float64(columns) / float64(im.Width())
+	scale_y := float64(rows) / float64(im.Height())
+	window_width := int(math.Min(float64(columns), math.Max(1, math.Floor(float64(crop_width)*scale_x+0.5))))
+	window_height := int(math.Min(float64(rows), math.Max(1, math.Floor(float64(crop_height)*scale_y+0.5))))
+	best_x, best_y := 0, 0
+	best_score, best_distance := int64(-1), math.MaxFloat64
+	for y := 0; y+window_height <= rows; y++ {
+		for x := 0; x+window_width <= columns; x++ {
+			score := sums[(y+window_height)*(columns+1)+x+window_width] - sums[y*(columns+1)+x+window_width] -
+				sums[(y+window_height)*(columns+1)+x] + sums[y*(columns+1)+x]
+			// prefer the most central window when scores tie
+			distance := math.Hypot(float64(2*x+window_width-columns), float64(2*y+window_height-rows))
+			if score > best_score || (score == best_score && distance < best_distance) {
+				best_x, best_y, best_score, best_distance = x, y, score, distance
+			}
+		}
+	}
+	geometry = &MagickGeometry{
+		Width:   crop_width,
+		Height:  crop_height,
+		Xoffset: clamp(int(math.Floor(float64(best_x)/scale_x+0.5)), 0, im.Width()-crop_width),
+		Yoffset: clamp(int(math.Floor(float64(best_y)/scale_y+0.5)), 0, im.Height()-crop_height),
+	}
+	if err = im.cropAndResize(geometry, width, height); err != nil {
+		return nil, err
+	}
+	return geometry, nil
+}
+
+// CropAtFocalPoint crops a width x height region from the image in place, keeping the focal point
+// fx, fy (from 0.0 to 1.0, relative to the image width and height) as close to the center of the
+// region as the image bounds allow. Regions larger than the image are limited to the image size.
+// The region actually cropped is returned.
+func (im *MagickImage) CropAtFocalPoint(width, height int, fx, fy float64) (geometry *MagickGeometry, err error) {
+	if width < 1 || height < 1 {
+		return nil, &MagickError{"error", "", "invalid crop size " + strconv.Itoa(width) + "x" + strconv.Itoa(height)}
+	}
+	geometry, err = im.focalWindow(clamp(width, 1, im.Width()), clamp(height, 1, im.Height()), fx, fy)
+	if err != nil {
+		return nil, err
+	}
+	region := strconv.Itoa(geometry.Width) + "x" + strconv.Itoa(geometry.Height) +
+		"+" + strconv.Itoa(geometry.Xoffset) + "+" + strconv.Itoa(geometry.Yoffset)
+	if err = im.CropWithGravity(region, NorthWestGravity); err != nil {
+		return nil, err
+	}
+	return geometry, nil
+}
+
+// ThumbnailAtFocalPoint makes a width x height thumbnail of the image in place, cropping the largest
+// region with the thumbnail's aspect ratio that keeps the focal point fx, fy (from 0.0 to 1.0, relative
+// to the image width and height) as centered as possible. The region cropped from the original image is returned.
+func (im *MagickImage) ThumbnailAtFocalPoint(width, height int, fx, fy float64) (geometry *MagickGeometry, err error) {
+	if width < 1 || height < 1 {
+		return nil, &MagickError{"error", "", "invalid thumbnail size " + strconv.Itoa(width) + "x" + strconv.Itoa(height)}
+	}
+	crop_width, crop_height := im.cropWindow(width, height)
+	geometry, err = im.focalWindow(crop_width, crop_height, fx, fy)
+	if err != nil {
+		return nil, err
+	}
+	if err = im.cropAndResize(geometry, width, height); err != nil {
+		return nil, err
+	}
+	return geometry, nil
+}
+
+// focalWindow positions a width x height region inside the image so the focal point fx, fy
+// is as close to its center as possible
+func (im *MagickImage) focalWindow(width, height int, fx, fy float64) (geometry *MagickGeometry, err error) {
+	if fx < 0 || fx > 1 || fy < 0 || fy > 1 {
+		return nil, &MagickError{"error", "", "focal point must be between 0.0 and 1.0"}
+	}
+	x := int(math.Floor(fx*float64(im.Width()) - float64(width)/2 + 0.5))
+	y := int(math.Floor(fy*float64(im.Height()) - float64(height)/2 + 0.5))
+	return &MagickGeometry{
+		Width:   width,
+		Height:  height,
+		Xoffset: clamp(x, 0, im.Width()-width),
+		Yoffset: clamp(y, 0, im.Height()-height),
+	}, nil
+}
+
+// cropWindow returns the size of the largest region with the aspect ratio of width x height
+// that fits inside the image
+func (im *MagickImage) cropWindow(width, height int) (crop_width, crop_height int) {
+	scale := math.Min(float64(im.Width())/float64(width), float64(im.Height())/float64(height))
+	crop_width = clamp(int(math.Floor(float64(width)*scale+0.5)), 1, im.Width())
+	crop_height = clamp(int(math.Floor(float64(height)*scale+0.5)), 1, im.Height())
+	return
+}
+
+// cropAndResize crops the image to geometry and resizes the result to exactly width x height
+func (im *MagickImage) cropAndResize(geometry *MagickGeometry, width, height int) (err error) {
+	region := strconv.Itoa(geometry.Width) + "x" + strconv.Itoa(geometry.Height) +
+		"+" + strconv.Itoa(geometry.Xoffset) + "+" + strconv.Itoa(geometry.Yoffset)
+	if err = im.CropWithGravity(region, NorthWestGravity); err != nil {
+		return err
+	}
+	return im.Resize(strconv.Itoa(width) + "x" + strconv.Itoa(height) + "!")
+}
+
+func clamp(value, low, high int) int {
+	if value > high {
+		value = high
+	}
+	if value < low {
+		value = low
+	}
+	return value
+}
+
+// Shadow adds a dropshadow to the current (transparent) image and stores the shadowed image in place
+// For more information about shadow options see: http://www.imagemagick.org/Usage/blur/#shadow
+func (im *MagickImage) Shadow(color string, opacity, sigma float32, xoffset, yoffset int) (err error) {
+	exception := C.AcquireExceptionInfo()
+	defer C.DestroyExceptionInfo(exception)
+	c_opacity := (C.double)(opacity)
+	c_sigma := (C.double)(sigma)
+	c_x := (C.ssize_t)(xoffset)
+	c_y := (C.ssize_t)(yoffset)
+	c_color := C.CString(color)
+	defer C.free(unsafe.Pointer(c_color))
+	new_image := C.AddShadowToImage(im.Image, c_color, c_opacity, c_sigma, c_x, c_y, exception)
+	if failed := C.CheckException(exception); failed == C.MagickTrue {
+		return ErrorFromExceptionInfo(exception)
+	}
+	im.ReplaceImage(new_image)
+	return nil
+}
+
+// FillBackgroundColor fills transparent areas of an image with a solid color and stores the filled image in place.
+// color can be any color format that image magick understands, see: http://www.imagemagick.org/ImageMagick-7.0.0/script/color.php#models
+func (im *MagickImage) FillBackgroundColor(color string) (err error) {
+	exception := C.AcquireExceptionInfo()
+	defer C.DestroyExceptionInfo(exception)
+	c_color := C.CString(color)
+	defer C.free(unsafe.Pointer(c_color))
+	new_image := C.FillBackgroundColor(im.Image, c_color, exception)
+	if failed := C.CheckException(exception); failed == C.MagickTrue {
+		return ErrorFromExceptionInfo(exception)
+	}
+	im.ReplaceImage(new_image)
+	return nil
+}
+
+// SeparateAlphaChannel replaces the Image with grayscale data from the image's Alpha Channel values
+func (im *MagickImage) SeparateAlphaChannel() (err error) {
+	exception := C.AcquireExceptionInfo()
+	defer C.DestroyExceptionInfo(exception)
+	new_image := C.SeparateAlphaChannel(im.Image, exception)
+	if failed := C.CheckException(exception); failed == C.MagickTrue {
+		return ErrorFromExceptionInfo(exception)
+	}
+	im.ReplaceImage(new_image)
+	return nil
+}
+
+// Negate inverts the colors in the image
+func (im *MagickImage) Negate() (err error) {
+	exception := C.AcquireExceptionInfo()
+	defer C.DestroyExceptionInfo(exception)
+	new_image := C.Negate(im.Image, exception)
+	if failed := C.CheckException(exception); failed == C.MagickTrue {
+		return ErrorFromExceptionInfo(exception)
+	}
+	im.ReplaceImage(new_image)
+	return nil
+}
+
+// Rotate rotates the image clockwise by degrees and stores the rotated image in place. For angles
+// that are not a multiple of 90 the canvas grows to fit the rotated image and the corners are
+// filled with color, which can be "none" for transparency.
+// color can be any color format that image magick understands, see: http://www.imagemagick.org/script/color.php
+func (im *MagickImage) Rotate(degrees float64, color string) (err error) {
+	exception := C.AcquireExceptionInfo()
+	defer C.DestroyExceptionInfo(exception)
+	c_color := C.CString(color)
+	defer C.free(unsafe.Pointer(c_color))
+	new_image := C.RotateWithBackground(im.Image, (C.double)(degrees), c_color, exception)
+	if failed := C.CheckException(exception); failed == C.MagickTrue {
+		if new_image != nil {
+			C.DestroyImage(new_image)
+		}
+		return ErrorFromExceptionInfo(exception)
+	}
+	if new_image == nil {
+		return &MagickError{"error", "", "could not rotate image"}
+	}
+	im.ReplaceImage(new_image)
+	return nil
+}
+
+// Flip mirrors the image vertically (top to bottom)
+func (im *MagickImage) Flip() (err error) {
+	exception := C.AcquireExceptionInfo()
+	defer C.DestroyExceptionInfo(exception)
+	new_image := C.FlipImage(im.Image, exception)
+	if failed := C.CheckException(exception); failed == C.MagickTrue {
+		return ErrorFromExceptionInfo(exception)
+	}
+	im.ReplaceImage(new_image)
+	return nil
+}
+
+// Flop mirrors the image horizontally (left to right)
+func (im *MagickImage) Flop() (err error) {
+	exception := C.AcquireExceptionInfo()
+	defer C.DestroyExceptionInfo(exception)
+	new_image := C.FlopImage(im.Image, exception)
+	if failed := C.CheckException(exception); failed == C.MagickTrue {
+		return ErrorFromExceptionInfo(exception)
+	}
+	im.ReplaceImage(new_image)
+	return nil
+}
+
+// Transpose mirrors the image along the top-left to bottom-right diagonal
+func (im *MagickImage) Transpose() (err error) {
+	exception := C.AcquireExceptionInfo()
+	defer C.DestroyExceptionInfo(exception)
+	new_image := C.TransposeImage(im.Image, exception)
+	if failed := C.CheckException(exception); failed == C.MagickTrue {
+		return ErrorFromExceptionInfo(exception)
+	}
+	im.ReplaceImage(new_image)
+	return nil
+}
+
+// Transverse mirrors the image along the bottom-left to top-right diagonal
+func (im *MagickImage) Transverse() (err error) {
+	exception := C.AcquireExceptionInfo()
+	defer C.DestroyExceptionInfo(exception)
+	new_image := C.TransverseImage(im.Image, exception)
+	if failed := C.CheckException(exception); failed == C.MagickTrue {
+		return ErrorFromExceptionInfo(exception)
+	}
+	im.ReplaceImage(new_image)
+	return nil
+}
+
+// Trim removes the edges of the image that match the color of its corners and stores the trimmed
+// image in place. fuzz is the tolerance in percent (0 to 100) for colors to count as matching, so
+// slightly noisy borders of scans can be removed too. Fully transparent edges are trimmed from
+// transparent images. The region that was kept is returned, its offsets are the amount removed
+// from the left and top.
+func (im *MagickImage) Trim(fuzz float64) (geometry *MagickGeometry, err error) {
+	if fuzz < 0 || fuzz > 100 {
+		return nil, &MagickError{"error", "", "fuzz must be between 0 and 100"}
+	}
+	exception := C.AcquireExceptionInfo()
+	defer C.DestroyExceptionInfo(exception)
+	var bounds C.RectangleInfo
+	new_image := C.TrimImageWithFuzz(im.Image, (C.double)(fuzz), &bounds, exception)
+	if failed := C.CheckException(exception); failed == C.MagickTrue {
+		if new_image != nil {
+			C.DestroyImage(new_image)
+		}
+		return nil, ErrorFromExceptionInfo(exception)
+	}
+	if new_image == nil {
+		return nil, &MagickError{"error", "", "image is a single color, nothing left after trim"}
+	}
+	im.ReplaceImage(new_image)
+	return &MagickGeometry{Width: int(bounds.width), Height: int(bounds.height), Xoffset: int(bounds.x), Yoffset: int(bounds.y)}, nil
+}
+
+// Composite draws overlay onto the image in place, combining the pixels with operator. The overlay
+// is positioned relative to gravity and offset by x and y. Use Dissolve to blend with an opacity.
+func (im *MagickImage) Composite(overlay *MagickImage, operator CompositeOperator, gravity Gravity, x, y int) (err error) {
+	return im.compose(overlay, operator, gravity, x, y, "")
+}
+
+// Dissolve draws overlay onto the image in place at opacity percent (0 to 100), positioned
+// relative to gravity and offset by x and y
+func (im *MagickImage) Dissolve(overlay *MagickImage, opacity float64, gravity Gravity, x, y int) (err error) {
+	if opacity < 0 || opacity > 100 {
+		return &MagickError{"error", "", "opacity must be between 0 and 100"}
+	}
+	return im.compose(overlay, DissolveCompositeOp, gravity, x, y, strconv.FormatFloat(opacity, 'f', -1, 64))
+}
+
+// Watermark stamps overlay onto the image in place. scale sizes the overlay relative to the width of
+// the image (e.g. 0.25 for a quarter of the width), 0 keeps it as is. opacity is in percent (0 to 100).
+// The overlay is placed according to gravity, or repeated across the whole image if tile is true.
+func (im *MagickImage) Watermark(overlay *MagickImage, scale, opacity float64, gravity Gravity, tile bool) (err error) {
+	if scale < 0 {
+		return &MagickError{"error", "", "scale can not be negative"}
+	}
+	if opacity <= 0 || opacity > 100 {
+		return &MagickError{"error", "", "opacity must be between 0 and 100"}
+	}
+	if overlay == nil || overlay.Image == nil {
+		return &MagickError{"error", "", "nil overlay passed to Watermark"}
+	}
+	mark, err := overlay.Clone()
+	if err != nil {
+		return err
+	}
+	defer mark.Destroy()
+	if scale > 0 {
+		width := int(math.Max(1, math.Floor(float64(im.Width())*scale+0.5)))
+		if err = mark.Resize(strconv.Itoa(width) + "x"); err != nil {
+			return err
+		}
+	}
+	stamp := func(gravity Gravity, x, y int) error {
+		if opacity < 100 {
+			return im.Dissolve(mark, opacity, gravity, x, y)
+		}
+		return im.Composite(mark, OverCompositeOp, gravity, x, y)
+	}
+	if !tile {
+		return stamp(gravity, 0, 0)
+	}
+	for y := 0; y < im.Height(); y += mark.Height() {
+		for x := 0; x < im.Width(); x += mark.Width() {
+			if err = stamp(NorthWestGravity, x, y); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (im *MagickImage) compose(overlay *MagickImage, operator CompositeOperator, gravity Gravity, x, y int, args string) (err error) {
+	if overlay == nil || overlay.Image == nil {
+		return &MagickError{"error", "", "nil overlay passed to Composite"}
+	}
+	exception := C.AcquireExceptionInfo()
+	defer C.DestroyExceptionInfo(exception)
+	var c_args *C.char
+	if args != "" {
+		c_args = C.CString(args)
+		defer C.free(unsafe.Pointer(c_args))
+	}
+	ok := C.CompositeWithGravity(im.Image, (C.CompositeOperator)(operator), overlay.Image, (C.GravityType)(gravity), (C.ssize_t)(x), (C.ssize_t)(y), c_args, exception)
+	if failed := C.CheckException(exception); failed == C.MagickTrue {
+		return ErrorFromExceptionInfo(exception)
+	}
+	if ok == C.MagickFalse {
+		return &MagickError{"error", "", "could not composite image"}
+	}
+	return
+}
+
+// Annotate renders text onto the image in place as described by options, e.g. font, size,
+// color, stroke and position. Lines are broken at newlines and, if options.WrapWidth is set,
+// between words so no line is wider than WrapWidth.
+func (im *MagickImage) Annotate(text string, options *TextOptions) (err error) {
+	if options == nil {
+		options = &TextOptions{}
+	}
+	text, err = im.wrapText(text, options)
+	if err != nil {
+		return err
+	}
+	offset := func(value int) string {
+		if value < 0 {
+			return strconv.Itoa(value)
+		}
+		return "+" + strconv.Itoa(value)
+	}
+	draw_info, err := im.textDrawInfo(text, options, offset(options.XOffset)+offset(options.YOffset))
+	if err != nil {
+		return err
+	}
+	defer C.DestroyDrawInfo(draw_info)
+	exception := C.AcquireExceptionInfo()
+	defer C.DestroyExceptionInfo(exception)
+	new_image := C.CloneImage(im.Image, 0, 0, C.MagickTrue, exception)
+	if failed := C.CheckException(exception); failed == C.MagickTrue {
+		if new_image != nil {
+			C.DestroyImage(new_image)
+		}
+		return ErrorFromExceptionInfo(exception)
+	}
+	// a font that can not be read is only reported on the image after the text was drawn
+	// with the default font, so draw on a copy that is thrown away on failure
+	C.ClearMagickException(&new_image.exception)
+	ok := C.AnnotateImage(new_image, draw_info)
+	C.InheritException(exception, &new_image.exception)
+	if failed := C.CheckException(exception); failed == C.MagickTrue {
+		C.DestroyImage(new_image)
+		return ErrorFromExceptionInfo(exception)
+	}
+	if ok == C.MagickFalse {
+		C.DestroyImage(new_image)
+		return &MagickError{"error", "", "could not annotate image"}
+	}
+	im.ReplaceImage(new_image)
+	return
+}
+
+// MeasureText returns the size text would have if it were rendered onto the image by Annotate
+// with the same options, so text can be fitted to a box before drawing
+func (im *MagickImage) MeasureText(text string, options *TextOptions) (metrics *TextMetrics, err error) {
+	if options == nil {
+		options = &TextOptions{}
+	}
+	text, err = im.wrapText(text, options)
+	if err != nil {
+		return nil, err
+	}
+	return im.measureText(text, options)
+}
+
+func (im *MagickImage) measureText(text string, options *TextOptions) (metrics *TextMetrics, err error) {
+	draw_info, err := im.textDrawInfo(text, options, "")
+	if err != nil {
+		return nil, err
+	}
+	defer C.DestroyDrawInfo(draw_info)
+	exception := C.AcquireExceptionInfo()
+	defer C.DestroyExceptionInfo(exception)
+	var type_metric C.TypeMetric
+	C.ClearMagickException(&im.Image.exception)
+	ok := C.GetMultilineTypeMetrics(im.Image, draw_info, &type_metric)
+	C.InheritException(exception, &im.Image.exception)
+	if failed := C.CheckException(exception); failed == C.MagickTrue {
+		return nil, ErrorFromExceptionInfo(exception)
+	}
+	if ok == C.MagickFalse {
+		return nil, &MagickError{"error", "", "could not measure text"}
+	}
+	return &TextMetrics{
+		Width:      float64(type_metric.width),
+		Height:     float64(type_metric.height),
+		Ascent:     float64(type_metric.ascent),
+		Descent:    float64(type_metric.descent),
+		MaxAdvance: float64(type_metric.max_advance),
+	}, nil
+}
+
+// wrapText breaks text into lines between words so that no line is wider than options.WrapWidth
+func (im *MagickImage) wrapText(text string, options *TextOptions) (wrapped string, err error) {
+	if options.WrapWidth <= 0 {
+		return text, nil
+	}
+	lines := []string{}
+	for _, paragraph := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			candidate := word
+			if line != "" {
+				candidate = line + " " + word
+			}
+			metrics, err := im.measureText(candidate, options)
+			if err != nil {
+				return "", err
+			}
+			if line != "" && metrics.Width > float64(options.WrapWidth) {
+				lines = append(lines, line)
+				line = word
+			} else {
+				line = candidate
+			}
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func (im *MagickImage) textDrawInfo(text string, options *TextOptions, geometry string) (draw_info *C.DrawInfo, err error) {
+	exception := C.AcquireExceptionInfo()
+	defer C.DestroyExceptionInfo(exception)
+	c_text := C.CString(text)
+	defer C.free(unsafe.Pointer(c_text))
+	optional := func(value string) *C.char {
+		if value == "" {
+			return nil
+		}
+		return C.CString(value)
+	}
+	c_font := optional(options.Font)
+	defer C.free(unsafe.Pointer(c_font))
+	c_fill := optional(options.Color)
+	defer C.free(unsafe.Pointer(c_fill))
+	c_stroke := optional(options.StrokeColor)
+	defer C.free(unsafe.Pointer(c_stroke))
+	c_geometry := optional(geometry)
+	defer C.free(unsafe.Pointer(c_geometry))
+	gravity := options.Gravity
+	if gravity == 0 {
+		gravity = NorthWestGravity
+	}
+	draw_info = C.AcquireTextDrawInfo(im.ImageInfo, c_text, c_font, (C.double)(options.PointSize), c_fill, c_stroke,
+		(C.double)(options.StrokeWidth), (C.GravityType)(gravity), c_geometry, exception)
+	if failed := C.CheckException(exception); failed == C.MagickTrue {
+		if draw_info != nil {
+			C.DestroyDrawInfo(draw_info)
+		}
+		return nil, ErrorFromExceptionInfo(exception)
+	}
+	if draw_info == nil {
+		return nil, &MagickError{"error", "", "could not prepare text"}
+	}
+	return draw_info, nil
+}
+
+// Strip strips the image of its extra meta (exif) data
+func (im *MagickImage) Strip() (err error) {
+	ok := C.StripImage(im.Image)
+	if ok == C.MagickFalse {
+		return &MagickError{"error", "", "could not strip image"}
+	}
+	return
+}
+
+// AutoOrientAndStrip applies the EXIF orientation with AutoOrient and then strips the image
+// with Strip, so photos keep the right way up once their meta data is gone
+func (im *MagickImage) AutoOrientAndStrip() (err error) {
+	if err = im.AutoOrient(); err != nil {
+		return err
+	}
+	return im.Strip()
+}
+
+// Orientation returns the EXIF orientation of the image, e.g. "TopLeft" or "RightTop",
+// or "Undefined" if the image has none
+func (im *MagickImage) Orientation() string {
+	return C.GoString(C.CommandOptionToMnemonic(C.MagickOrientationOptions, (C.ssize_t)(im.Image.orientation)))
+}
+
+// setOrientation sets the EXIF orientation of the image by name, e.g. "TopLeft" or "RightTop"
+func (im *MagickImage) setOrientation(orientation string) (err error) {
+	c_orientation := C.CString(orientation)
+	defer C.free(unsafe.Pointer(c_orientation))
+	value := C.ParseCommandOption(C.MagickOrientationOptions, C.MagickFalse, c_orientation)
+	if value < 0 {
+		return &MagickError{"error", "", "unrecognized orientation " + orientation}
+	}
+	im.Image.orientation = (C.OrientationType)(value)
+	return nil
+}
+
+// AutoOrient rotates and flips the image in place as its EXIF orientation describes, so it displays
+// the right way up without the orientation, which is then reset to TopLeft. Images without
+// an orientation or already at TopLeft are left as they are.
+func (im *MagickImage) AutoOrient() (err error) {
+	if im.Image.orientation == C.UndefinedOrientation || im.Image.orientation == C.TopLeftOrientation {
+		return nil
+	}
+	exception := C.AcquireExceptionInfo()
+	defer C.DestroyExceptionInfo(exception)
+	new_image := C.OrientImage(im.Image, exception)
+	if failed := C.CheckException(exception); failed == C.MagickTrue {
+		if new_image != nil {
+			C.DestroyImage(new_image)
+		}
+		return ErrorFromExceptionInfo(exception)
+	}
+	if new_image == nil {
+		return &MagickError{"error", "", "could not orient image"}
+	}
+	im.ReplaceImage(new_image)
+	return nil
+}
+
+// ToBlob takes a (transformed) MagickImage and returns a byte slice in the format you specify with extension.
+// Magick uses the extension to transform the image in to the proper encoding (e.g. "jpg", "png")
+func (im *MagickImage) ToBlob(extension string) (blob []byte, err error) {
 	exception := C.AcquireExceptionInfo()
 	defer C.DestroyExceptionInfo(exception)
 	c_outpath := C.CString("image." + extension)
@@ -476,6 +2338,174 @@ func (im *MagickImage) ToBlob(extension string) (blob []byte, err error) {
 	return C.GoBytes(char_pointer, (C.int)(outlength)), nil
 }
 
+// EncodeTo encodes the (transformed) MagickImage in the format given by extension (e.g. "jpg", "png")
+// and streams the output to w without building the whole blob in memory first. Encoding failures are
+// returned as MagickErrors, failures writing to w as a WriteError with the number of bytes written.
+func (im *MagickImage) EncodeTo(w io.Writer, extension string) (n int64, err error) {
+	exception := C.AcquireExceptionInfo()
+	defer C.DestroyExceptionInfo(exception)
+	info := C.CloneImageInfo(im.ImageInfo)
+	defer C.DestroyImageInfo(info)
+	c_outpath := C.CString("image." + extension)
+	defer C.free(unsafe.Pointer(c_outpath))
+	C.SetImageInfoFilename(info, c_outpath)
+	destination := newWriteStream(w)
+	handle := cgo.NewHandle(destination)
+	defer handle.Delete()
+	var seekable C.int
+	if destination.seeker != nil {
+		seekable = 1
+	}
+	C.WriteImageToStream(info, im.Image, (C.uintptr_t)(handle), seekable, exception)
+	if destination.err != nil {
+		return destination.written, &WriteError{destination.written, destination.err}
+	}
+	if failed := C.CheckException(exception); failed == C.MagickTrue {
+		return destination.written, ErrorFromExceptionInfo(exception)
+	}
+	return destination.written, nil
+}
+
+// WriteTo implements io.WriterTo by encoding the MagickImage in its current format (see Type)
+// to w. Images that were not decoded from a format that can be written, like those made by
+// NewCanvas or NewFromPixels, return an error. Use EncodeTo to choose the output format.
+func (im *MagickImage) WriteTo(w io.Writer) (n int64, err error) {
+	format := im.Type()
+	c_format := C.CString(format)
+	defer C.free(unsafe.Pointer(c_format))
+	if format == "" || C.GetEncoderSupport(c_format) != C.MagickTrue {
+		return 0, &MagickError{"error", "", "image has no format \"" + format + "\" can be written in, use EncodeTo"}
+	}
+	return im.EncodeTo(w, strings.ToLower(format))
+}
+
+// ToBlobWith is like ToBlob but encodes the image according to options. The options are
+// validated against the format given by extension and the image itself is not modified.
+func (im *MagickImage) ToBlobWith(extension string, options *EncodeOptions) (blob []byte, err error) {
+	if options == nil {
+		options = &EncodeOptions{}
+	}
+	if err = options.validate(extension); err != nil {
+		return nil, err
+	}
+	encode, err := im.Clone()
+	if err != nil {
+		return nil, err
+	}
+	defer encode.Destroy()
+	if err = options.apply(encode); err != nil {
+		return nil, err
+	}
+	return encode.ToBlob(extension)
+}
+
+// ToFileWith is like ToFile but encodes the image according to options. The options are
+// validated against the format given by the extension of filename and the image itself is not modified.
+func (im *MagickImage) ToFileWith(filename string, options *EncodeOptions) (err error) {
+	if options == nil {
+		options = &EncodeOptions{}
+	}
+	if err = options.validate(strings.TrimPrefix(filepath.Ext(filename), ".")); err != nil {
+		return err
+	}
+	encode, err := im.Clone()
+	if err != nil {
+		return err
+	}
+	defer encode.Destroy()
+	if err = options.apply(encode); err != nil {
+		return err
+	}
+	return encode.ToFile(filename)
+}
+
+// Renditions resizes and encodes the image once for every spec in specs and returns the results
+// keyed by spec Name. Each size is derived from the smallest already resized intermediate that is
+// still larger than it, and encoded to every format requested at that size. The image itself is
+// not modified. Failures are reported per rendition in Rendition.Err, including specs that share
+// a Name, none of which are rendered.
+func (im *MagickImage) Renditions(specs []RenditionSpec) (renditions map[string]*Rendition) {
+	renditions = make(map[string]*Rendition)
+	type size struct {
+		width, height int
+	}
+	bySize := make(map[size][]RenditionSpec)
+	sizes := []size{}
+	names := make(map[string]int)
+	for i := range specs {
+		if specs[i].Name == "" {
+			names[specs[i].Geometry+"."+specs[i].Format]++
+		} else {
+			names[specs[i].Name]++
+		}
+	}
+	for _, spec := range specs {
+		if spec.Name == "" {
+			spec.Name = spec.Geometry + "." + spec.Format
+		}
+		if names[spec.Name] > 1 {
+			renditions[spec.Name] = &Rendition{Err: &MagickError{"error", "", "duplicate rendition name " + spec.Name}}
+			continue
+		}
+		geometry, err := im.ParseGeometry(spec.Geometry)
+		if err != nil {
+			renditions[spec.Name] = &Rendition{Err: err}
+			continue
+		}
+		target := size{geometry.Width, geometry.Height}
+		if _, ok := bySize[target]; !ok {
+			sizes = append(sizes, target)
+		}
+		bySize[target] = append(bySize[target], spec)
+	}
+	// largest first, so every size can be derived from a larger intermediate
+	sort.Slice(sizes, func(i, j int) bool {
+		return sizes[i].width*sizes[i].height > sizes[j].width*sizes[j].height
+	})
+	intermediates := []*MagickImage{}
+	defer func() {
+		for _, intermediate := range intermediates {
+			intermediate.Destroy()
+		}
+	}()
+	for _, target := range sizes {
+		resized, err := im.rendition(intermediates, target.width, target.height)
+		for _, spec := range bySize[target] {
+			if err != nil {
+				renditions[spec.Name] = &Rendition{Err: err}
+				continue
+			}
+			blob, err := resized.ToBlobWith(spec.Format, spec.Options)
+			renditions[spec.Name] = &Rendition{Blob: blob, Width: resized.Width(), Height: resized.Height(), Err: err}
+		}
+		if err == nil {
+			intermediates = append(intermediates, resized)
+		}
+	}
+	return renditions
+}
+
+// rendition returns a copy of the image resized to width x height, derived from the smallest
+// of the image and intermediates that is at least as large
+func (im *MagickImage) rendition(intermediates []*MagickImage, width, height int) (resized *MagickImage, err error) {
+	base := im
+	for _, intermediate := range intermediates {
+		if intermediate.Width() >= width && intermediate.Height() >= height &&
+			intermediate.Width()*intermediate.Height() < base.Width()*base.Height() {
+			base = intermediate
+		}
+	}
+	resized, err = base.Clone()
+	if err != nil {
+		return nil, err
+	}
+	if err = resized.Resize(strconv.Itoa(width) + "x" + strconv.Itoa(height) + "!"); err != nil {
+		resized.Destroy()
+		return nil, err
+	}
+	return resized, nil
+}
+
 // ToFile writes the (transformed) MagickImage to the regular file at filename. Magick determines
 // the encoding of the output file by the extension given to the filename (e.g. "image.jpg", "image.png")
 func (im *MagickImage) ToFile(filename string) (err error) {
@@ -493,3 +2523,270 @@ func (im *MagickImage) ToFile(filename string) (err error) {
 	}
 	return nil
 }
+
+// NewList returns an empty MagickImageList that frames can be appended to
+func NewList() (list *MagickImageList) {
+	return &MagickImageList{Image: nil, ImageInfo: C.AcquireImageInfo()}
+}
+
+// NewListFromFile loads every frame or page of the file at filename into a MagickImageList.
+// Exceptions are returned as MagickErrors.
+func NewListFromFile(filename string) (list *MagickImageList, err error) {
+	im, err := NewFromFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return &MagickImageList{Image: im.Image, ImageInfo: im.ImageInfo}, nil
+}
+
+// NewListFromBlob is like NewFromBlob but keeps every frame or page of the image
+// data in a MagickImageList
+func NewListFromBlob(blob []byte, extension string) (list *MagickImageList, err error) {
+	im, err := NewFromBlob(blob, extension)
+	if err != nil {
+		return nil, err
+	}
+	return &MagickImageList{Image: im.Image, ImageInfo: im.ImageInfo}, nil
+}
+
+// Destroy frees the C memory for every frame in the list. Should be called after processing is done.
+func (list *MagickImageList) Destroy() (err error) {
+	if list.Image != nil {
+		C.DestroyImageList(list.Image)
+	}
+	if list.ImageInfo != nil {
+		C.DestroyImageInfo(list.ImageInfo)
+	}
+	list.Image = nil
+	list.ImageInfo = nil
+	return
+}
+
+// Len returns the number of frames in the list
+func (list *MagickImageList) Len() int {
+	if list.Image == nil {
+		return 0
+	}
+	return int(C.GetImageListLength(list.Image))
+}
+
+// Frame returns a copy of the frame at index i as a standalone MagickImage.
+// Changes to the returned image do not affect the list.
+func (list *MagickImageList) Frame(i int) (im *MagickImage, err error) {
+	if i < 0 || i >= list.Len() {
+		return nil, &MagickError{"error", "", "frame " + strconv.Itoa(i) + " out of range"}
+	}
+	exception := C.AcquireExceptionInfo()
+	defer C.DestroyExceptionInfo(exception)
+	frame := C.GetImageFromList(list.Image, (C.ssize_t)(i))
+	new_image := C.CloneImage(frame, 0, 0, C.MagickTrue, exception)
+	if failed := C.CheckException(exception); failed == C.MagickTrue {
+		return nil, ErrorFromExceptionInfo(exception)
+	}
+	return &MagickImage{Image: new_image, ImageInfo: C.CloneImageInfo(list.ImageInfo)}, nil
+}
+
+// Append adds a copy of frame to the end of the list
+func (list *MagickImageList) Append(frame *MagickImage) (err error) {
+	exception := C.AcquireExceptionInfo()
+	defer C.DestroyExceptionInfo(exception)
+	new_image := C.CloneImage(frame.Image, 0, 0, C.MagickTrue, exception)
+	if failed := C.CheckException(exception); failed == C.MagickTrue {
+		return ErrorFromExceptionInfo(exception)
+	}
+	images := list.Image
+	C.AppendImageToList(&images, new_image)
+	list.Image = images
+	return nil
+}
+
+// Each calls fn with every frame in the list as a MagickImage, so any MagickImage operation
+// can be applied per frame. The frames as left by fn replace the frames of the list. If fn
+// returns an error iteration stops and the list is left unchanged.
+func (list *MagickImageList) Each(fn func(i int, frame *MagickImage) error) (err error) {
+	exception := C.AcquireExceptionInfo()
+	defer C.DestroyExceptionInfo(exception)
+	frames := C.NewImageList()
+	i := 0
+	for image := C.GetFirstImageInList(list.Image); image != nil; image = C.GetNextImageInList(image) {
+		new_image := C.CloneImage(image, 0, 0, C.MagickTrue, exception)
+		if failed := C.CheckException(exception); failed == C.MagickTrue {
+			if frames != nil {
+				C.DestroyImageList(frames)
+			}
+			return ErrorFromExceptionInfo(exception)
+		}
+		frame := &MagickImage{Image: new_image, ImageInfo: C.CloneImageInfo(list.ImageInfo)}
+		if err = fn(i, frame); err != nil {
+			frame.Destroy()
+			if frames != nil {
+				C.DestroyImageList(frames)
+			}
+			return err
+		}
+		C.AppendImageToList(&frames, frame.Image)
+		frame.Image = nil
+		frame.Destroy()
+		i++
+	}
+	if list.Image != nil {
+		C.DestroyImageList(list.Image)
+	}
+	list.Image = frames
+	return nil
+}
+
+// ReplaceImages Replaces the underlying list of images, freeing the old one
+func (list *MagickImageList) ReplaceImages(new_images *C.Image) {
+	if list.Image != nil {
+		C.DestroyImageList(list.Image)
+	}
+	list.Image = new_images
+}
+
+// Coalesce replaces the frames of an animation with full size frames as they appear when
+// played, undoing any layer optimization. Frame delays, loop count and disposal are kept.
+func (list *MagickImageList) Coalesce() (err error) {
+	if list.Image == nil {
+		return &MagickError{"error", "", "empty image list"}
+	}
+	exception := C.AcquireExceptionInfo()
+	defer C.DestroyExceptionInfo(exception)
+	new_images := C.CoalesceImages(list.Image, exception)
+	if failed := C.CheckException(exception); failed == C.MagickTrue {
+		return ErrorFromExceptionInfo(exception)
+	}
+	list.ReplaceImages(new_images)
+	return nil
+}
+
+// Optimize re-optimizes coalesced animation frames into smaller layers that only
+// contain the pixels that change between frames
+func (list *MagickImageList) Optimize() (err error) {
+	if list.Image == nil {
+		return &MagickError{"error", "", "empty image list"}
+	}
+	exception := C.AcquireExceptionInfo()
+	defer C.DestroyExceptionInfo(exception)
+	new_images := C.OptimizeImageLayers(list.Image, exception)
+	if failed := C.CheckException(exception); failed == C.MagickTrue {
+		return ErrorFromExceptionInfo(exception)
+	}
+	C.OptimizeImageTransparency(new_images, exception)
+	if failed := C.CheckException(exception); failed == C.MagickTrue {
+		C.DestroyImageList(new_images)
+		return ErrorFromExceptionInfo(exception)
+	}
+	list.ReplaceImages(new_images)
+	return nil
+}
+
+// Resize resizes every frame of the list based on the geometry string passed with
+// MagickImage.Resize. Animations are coalesced into full frames before and re-optimized
+// into layers after resizing, keeping frame delays and loop count. Disposal methods are
+// chosen again by the optimizer, as the original ones describe layers that no longer exist.
+// Pages of a document are resized independently of each other.
+// For more info about Geometry see http://www.imagemagick.org/script/command-line-processing.php#geometry
+func (list *MagickImageList) Resize(geometry string) (err error) {
+	return list.transformFrames(func(i int, frame *MagickImage) error {
+		return frame.Resize(geometry)
+	})
+}
+
+// Crop crops every frame of the list based on the geometry string passed with MagickImage.Crop.
+// Animations are coalesced and re-optimized as with Resize, so their disposal methods are chosen
+// again. Pages of a document are cropped independently of each other.
+// For more info about Geometry see http://www.imagemagick.org/script/command-line-processing.php#geometry
+func (list *MagickImageList) Crop(geometry string) (err error) {
+	return list.transformFrames(func(i int, frame *MagickImage) error {
+		return frame.Crop(geometry)
+	})
+}
+
+// IsAnimation reports whether the list holds the frames of an animation, i.e. more than one
+// frame decoded from an animated format such as GIF or WebP, rather than the pages of a document
+func (list *MagickImageList) IsAnimation() bool {
+	if list.Len() < 2 {
+		return false
+	}
+	switch strings.Trim(string(C.GoBytes(unsafe.Pointer(&list.Image.magick), 4096)), "\x00") {
+	case "GIF", "GIF87", "WEBP", "MNG", "APNG":
+		return true
+	}
+	return false
+}
+
+func (list *MagickImageList) transformFrames(fn func(i int, frame *MagickImage) error) (err error) {
+	if !list.IsAnimation() {
+		return list.Each(fn)
+	}
+	type timing struct {
+		delay      C.size_t
+		ticks      C.ssize_t
+		iterations C.size_t
+	}
+	timings := []timing{}
+	for image := C.GetFirstImageInList(list.Image); image != nil; image = C.GetNextImageInList(image) {
+		timings = append(timings, timing{image.delay, image.ticks_per_second, image.iterations})
+	}
+	if err = list.Coalesce(); err != nil {
+		return err
+	}
+	if err = list.Each(fn); err != nil {
+		return err
+	}
+	C.ResetImageListPage(list.Image)
+	if err = list.Optimize(); err != nil {
+		return err
+	}
+	if list.Len() == len(timings) {
+		i := 0
+		for image := C.GetFirstImageInList(list.Image); image != nil; image = C.GetNextImageInList(image) {
+			image.delay, image.ticks_per_second, image.iterations = timings[i].delay, timings[i].ticks, timings[i].iterations
+			i++
+		}
+	}
+	return nil
+}
+
+// ToBlob encodes every frame in the list in the format you specify with extension (e.g. "gif", "pdf")
+// and returns the result as a byte slice
+func (list *MagickImageList) ToBlob(extension string) (blob []byte, err error) {
+	if list.Image == nil {
+		return nil, &MagickError{"error", "", "empty image list"}
+	}
+	exception := C.AcquireExceptionInfo()
+	defer C.DestroyExceptionInfo(exception)
+	c_outpath := C.CString("image." + extension)
+	defer C.free(unsafe.Pointer(c_outpath))
+	C.SetImageInfoFilename(list.ImageInfo, c_outpath)
+	var outlength (C.size_t)
+	outblob := C.ImagesToBlob(list.ImageInfo, list.Image, &outlength, exception)
+	if failed := C.CheckException(exception); failed == C.MagickTrue {
+		return nil, ErrorFromExceptionInfo(exception)
+	}
+	char_pointer := unsafe.Pointer(outblob)
+	defer C.free(char_pointer)
+	return C.GoBytes(char_pointer, (C.int)(outlength)), nil
+}
+
+// ToFile writes every frame in the list to the regular file at filename. Magick determines
+// the encoding of the output file by the extension given to the filename (e.g. "image.gif")
+func (list *MagickImageList) ToFile(filename string) (err error) {
+	if list.Image == nil {
+		return &MagickError{"error", "", "empty image list"}
+	}
+	exception := C.AcquireExceptionInfo()
+	defer C.DestroyExceptionInfo(exception)
+	c_outpath := C.CString(filename)
+	defer C.free(unsafe.Pointer(c_outpath))
+	C.SetImageInfoFilename(list.ImageInfo, c_outpath)
+	success := C.WriteImages(list.ImageInfo, list.Image, c_outpath, exception)
+	if failed := C.CheckException(exception); failed == C.MagickTrue {
+		return ErrorFromExceptionInfo(exception)
+	}
+	if success != C.MagickTrue {
+		return &MagickError{"fatal", "", "could not write to " + filename + " for unknown reason"}
+	}
+	return nil
+}
@@ -1,7 +1,12 @@
 package magick
 
 import (
+	"bytes"
+	"errors"
 	"github.com/bmizerany/assert"
+	goimage "image"
+	"image/color"
+	"image/draw"
 	"io/ioutil"
 	"log"
 	"os"
@@ -83,6 +88,78 @@ func TestBadDataFromBlob(t *testing.T) {
 	error = nil
 }
 
+func TestDetectFormat(t *testing.T) {
+	source, _ := ioutil.ReadFile("test/heart_original.png")
+	format, mimeType, err := DetectFormat(source)
+	assert.T(t, err == nil)
+	assert.Equal(t, "PNG", format)
+	assert.Equal(t, "image/png", mimeType)
+
+	source, _ = ioutil.ReadFile("test/heart_original.pdf")
+	format, mimeType, err = DetectFormat(source)
+	assert.T(t, err == nil)
+	assert.Equal(t, "PDF", format)
+	assert.Equal(t, "application/pdf", mimeType)
+
+	_, _, err = DetectFormat([]byte{})
+	assert.T(t, err != nil)
+}
+
+func TestImageFromBlobAuto(t *testing.T) {
+	source, _ := ioutil.ReadFile("test/heart_original.png")
+	image, err := NewFromBlobAuto(source)
+	assert.T(t, err == nil)
+	assert.T(t, image != nil)
+	assert.Equal(t, "PNG", image.Type())
+	assert.Equal(t, 600, image.Width())
+
+	image, err = NewFromBlobAuto([]byte{})
+	assert.T(t, err != nil)
+	assert.T(t, image == nil)
+}
+
+func TestImageFromReader(t *testing.T) {
+	file, err := os.Open("test/heart_original.png")
+	assert.T(t, err == nil)
+	defer file.Close()
+	image, err := NewFromReader(file, "png")
+	assert.T(t, err == nil)
+	assert.T(t, image != nil)
+	assert.Equal(t, 600, image.Width())
+	assert.Equal(t, 552, image.Height())
+
+	source, _ := ioutil.ReadFile("test/heart_original.png")
+	image, err = NewFromReader(bytes.NewBuffer(source), "png")
+	assert.T(t, err == nil)
+	assert.Equal(t, 600, image.Width())
+
+	// a seekable reader that is past a header of its own is only read from where it is
+	framed := bytes.NewReader(append([]byte("HEADER"), source...))
+	header := make([]byte, 6)
+	framed.Read(header)
+	image, err = NewFromReader(framed, "png")
+	assert.T(t, err == nil)
+	assert.Equal(t, 600, image.Width())
+
+	image, err = NewFromReader(bytes.NewBuffer([]byte("blah")), "jpg")
+	assert.T(t, err != nil)
+	assert.T(t, image == nil)
+
+	image, err = NewFromReader(file, "")
+	assert.T(t, err != nil)
+	assert.T(t, image == nil)
+}
+
+func TestPDFFromReader(t *testing.T) {
+	file, err := os.Open("test/heart_original.pdf")
+	assert.T(t, err == nil)
+	defer file.Close()
+	image, err := NewFromReader(file, "pdf")
+	assert.T(t, err == nil)
+	assert.T(t, image != nil)
+	assert.T(t, image.Image != nil)
+}
+
 func TestPDFFromBlob(t *testing.T) {
 	filename := "test/heart_original.pdf"
 	source, _ := ioutil.ReadFile(filename)
@@ -93,12 +170,247 @@ func TestPDFFromBlob(t *testing.T) {
 	assert.T(t, image.ImageInfo != nil)
 }
 
+func TestPing(t *testing.T) {
+	info, err := Ping("test/heart_original.png")
+	assert.T(t, err == nil)
+	assert.T(t, info != nil)
+	assert.Equal(t, 600, info.Width)
+	assert.Equal(t, 552, info.Height)
+	assert.Equal(t, "PNG", info.Format)
+	assert.Equal(t, 1, info.Frames)
+	assert.T(t, info.Colorspace != "")
+
+	info, err = Ping("test/heart_whatwhat.png")
+	assert.T(t, err != nil)
+	assert.T(t, info == nil)
+}
+
+func TestPingBlob(t *testing.T) {
+	source, _ := ioutil.ReadFile("test/heart_original.png")
+	info, err := PingBlob(source, "png")
+	assert.T(t, err == nil)
+	assert.Equal(t, 600, info.Width)
+	assert.Equal(t, 552, info.Height)
+	assert.Equal(t, "PNG", info.Format)
+
+	info, err = PingBlob([]byte("blah"), "jpg")
+	assert.T(t, err != nil)
+	assert.T(t, info == nil)
+}
+
+func TestNewCanvas(t *testing.T) {
+	image, err := NewCanvas(100, 50, "#F00")
+	assert.T(t, err == nil)
+	assert.T(t, image != nil)
+	assert.Equal(t, 100, image.Width())
+	assert.Equal(t, 50, image.Height())
+	_, err = image.ToBlob("png")
+	assert.T(t, err == nil)
+
+	image, err = NewCanvas(100, 50, "notacolor")
+	assert.T(t, err != nil)
+	assert.T(t, image == nil)
+
+	image, err = NewCanvas(0, 50, "red")
+	assert.T(t, err != nil)
+	assert.T(t, image == nil)
+}
+
+func TestNewGradients(t *testing.T) {
+	image, err := NewLinearGradient(200, 100, "red", "#00F")
+	assert.T(t, err == nil)
+	assert.Equal(t, 200, image.Width())
+	assert.Equal(t, 100, image.Height())
+
+	image, err = NewRadialGradient(200, 200, "white", "black")
+	assert.T(t, err == nil)
+	assert.Equal(t, 200, image.Width())
+	err = image.Resize("100x100")
+	assert.T(t, err == nil)
+	assert.Equal(t, 100, image.Width())
+}
+
+func TestImageFromBlobWithOptions(t *testing.T) {
+	source, _ := ioutil.ReadFile("test/heart_original.pdf")
+	image, err := NewFromBlob(source, "pdf")
+	assert.T(t, err == nil)
+	width := image.Width()
+
+	image, err = NewFromBlobWithOptions(source, "pdf", &LoadOptions{Scene: 0, Scenes: 1, Density: 144})
+	assert.T(t, err == nil)
+	assert.T(t, image != nil)
+	assert.T(t, image.Width() > width)
+	assert.T(t, image.ImageInfo.density == nil)
+	assert.T(t, image.ImageInfo.number_scenes == 0)
+
+	image, err = NewFromBlobWithOptions(source, "pdf", &LoadOptions{Colorspace: "Gray"})
+	assert.T(t, err == nil)
+	assert.T(t, image != nil)
+
+	image, err = NewFromBlobWithOptions(source, "pdf", &LoadOptions{Colorspace: "blurgh"})
+	assert.T(t, err != nil)
+	assert.T(t, image == nil)
+
+	image, err = NewFromBlobWithOptions(source, "pdf", &LoadOptions{Scene: -1})
+	assert.T(t, err != nil)
+	assert.T(t, image == nil)
+
+	image, err = NewFromBlobWithOptions(source, "pdf", &LoadOptions{Scene: 1})
+	assert.T(t, err != nil)
+	assert.T(t, image == nil)
+}
+
+func setupPDF(t *testing.T) (blob []byte) {
+	list := setupList(t)
+	defer list.Destroy()
+	blob, err := list.ToBlob("pdf")
+	assert.T(t, err == nil)
+	return
+}
+
+func TestLoadPage(t *testing.T) {
+	source := setupPDF(t)
+	list, err := NewListFromBlob(source, "pdf")
+	assert.T(t, err == nil)
+	assert.Equal(t, 3, list.Len())
+	list.Destroy()
+
+	image, err := NewFromBlobWithOptions(source, "pdf", &LoadOptions{Scene: 2, Scenes: 1, Density: 150})
+	assert.T(t, err == nil)
+	pages := &MagickImageList{Image: image.Image, ImageInfo: image.ImageInfo}
+	assert.Equal(t, 1, pages.Len())
+	pixel, err := image.ExportPixels(image.Width()/2, image.Height()/2, 1, 1, "RGB", CharPixel)
+	assert.T(t, err == nil)
+	assert.T(t, pixel[0] < 50)
+	assert.T(t, pixel[1] < 50)
+	assert.T(t, pixel[2] > 200)
+
+	image, err = NewFromBlobWithOptions(source, "pdf", &LoadOptions{Scene: 0, Scenes: 1})
+	assert.T(t, err == nil)
+	pixel, _ = image.ExportPixels(image.Width()/2, image.Height()/2, 1, 1, "RGB", CharPixel)
+	assert.T(t, pixel[0] > 200)
+	assert.T(t, pixel[2] < 50)
+}
+
+func TestImageFromFileWithOptions(t *testing.T) {
+	image, err := NewFromFileWithOptions("test/heart_original.pdf", nil)
+	assert.T(t, err == nil)
+	width := image.Width()
+	image, err = NewFromFileWithOptions("test/heart_original.pdf", &LoadOptions{Scene: 0, Scenes: 1, Density: 144})
+	assert.T(t, err == nil)
+	assert.T(t, image.Width() > width)
+	// load options are not kept for writing
+	assert.T(t, image.ImageInfo.density == nil)
+	assert.T(t, image.ImageInfo.number_scenes == 0)
+
+	image, err = NewFromFileWithOptions("test/heart_original.png", nil)
+	assert.T(t, err == nil)
+	assert.Equal(t, 552, image.Height())
+}
+
+func TestSizeHint(t *testing.T) {
+	source, err := setupImage(t).ToBlob("jpg")
+	assert.T(t, err == nil)
+	image, err := NewFromBlobWithOptions(source, "jpg", &LoadOptions{SizeHint: "150x138"})
+	assert.T(t, err == nil)
+	assert.T(t, image.Width() >= 150)
+	assert.T(t, image.Width() < 600)
+	assert.T(t, image.Height() >= 138)
+	err = image.Resize("150x138!")
+	assert.T(t, err == nil)
+	assert.Equal(t, 150, image.Width())
+
+	image, err = NewFromFileWithOptions("test/heart_original.png", &LoadOptions{SizeHint: "150x138"})
+	assert.T(t, err == nil)
+	assert.Equal(t, 600, image.Width())
+
+	image, err = NewFromBlobWithOptions(source, "jpg", &LoadOptions{SizeHint: "blurgh"})
+	assert.T(t, err != nil)
+	assert.T(t, image == nil)
+}
+
+func TestPageCount(t *testing.T) {
+	source, _ := ioutil.ReadFile("test/heart_original.pdf")
+	count, err := PageCount(source, "pdf")
+	assert.T(t, err == nil)
+	assert.Equal(t, 1, count)
+
+	count, err = PageCount(setupPDF(t), "pdf")
+	assert.T(t, err == nil)
+	assert.Equal(t, 3, count)
+
+	_, err = PageCount([]byte{}, "pdf")
+	assert.T(t, err != nil)
+}
+
 func TestParseGeometry(t *testing.T) {
 	image := setupImage(t)
 	geometry, err := image.ParseGeometry("100x100>")
 	assert.T(t, err == nil)
 	assert.T(t, geometry != nil)
 	assert.Equal(t, 100, geometry.Width)
+	assert.Equal(t, GeometryShrinkOnly, geometry.Flags)
+
+	geometry, err = image.ParseGeometry("300x300^")
+	assert.T(t, err == nil)
+	assert.Equal(t, 326, geometry.Width)
+	assert.Equal(t, 300, geometry.Height)
+	assert.Equal(t, GeometryFill, geometry.Flags)
+
+	geometry, err = image.ParseGeometry("50%")
+	assert.T(t, err == nil)
+	assert.Equal(t, 300, geometry.Width)
+	assert.Equal(t, 276, geometry.Height)
+	assert.T(t, geometry.Flags&GeometryPercent != 0)
+
+	geometry, err = image.ParseGeometry("100x100!")
+	assert.T(t, err == nil)
+	assert.Equal(t, GeometryForce, geometry.Flags)
+
+	_, err = image.ParseGeometry("blurgh")
+	assert.T(t, err != nil)
+}
+
+func TestResizeGeometryFlags(t *testing.T) {
+	image := setupImage(t)
+	err := image.Resize("300x300")
+	assert.T(t, err == nil)
+	assert.Equal(t, 300, image.Width())
+	assert.Equal(t, 276, image.Height())
+
+	image = setupImage(t)
+	err = image.Resize("300x300^")
+	assert.T(t, err == nil)
+	assert.Equal(t, 326, image.Width())
+	assert.Equal(t, 300, image.Height())
+
+	image = setupImage(t)
+	err = image.Resize("1000x1000>")
+	assert.T(t, err == nil)
+	assert.Equal(t, 600, image.Width())
+	assert.Equal(t, 552, image.Height())
+
+	image = setupImage(t)
+	err = image.Resize("100x100<")
+	assert.T(t, err == nil)
+	assert.Equal(t, 600, image.Width())
+
+	image = setupImage(t)
+	err = image.Resize("1000x1000<")
+	assert.T(t, err == nil)
+	assert.Equal(t, 1000, image.Width())
+
+	image = setupImage(t)
+	err = image.Resize("50%")
+	assert.T(t, err == nil)
+	assert.Equal(t, 300, image.Width())
+	assert.Equal(t, 276, image.Height())
+
+	image = setupImage(t)
+	err = image.Resize("10000@")
+	assert.T(t, err == nil)
+	assert.T(t, image.Width()*image.Height() <= 10000)
+	assert.T(t, image.Width() > 90)
 }
 
 func TestResizeRatio(t *testing.T) {
@@ -114,6 +426,60 @@ func TestStrip(t *testing.T) {
 	assert.T(t, err == nil)
 }
 
+func TestAutoOrient(t *testing.T) {
+	image := setupImage(t)
+	assert.Equal(t, "Undefined", image.Orientation())
+	err := image.AutoOrient()
+	assert.T(t, err == nil)
+	assert.Equal(t, "Undefined", image.Orientation())
+	assert.Equal(t, 600, image.Width())
+	assert.Equal(t, 552, image.Height())
+
+	err = image.setOrientation("blurgh")
+	assert.T(t, err != nil)
+
+	tests := []struct {
+		orientation   string
+		width, height int
+		x, y          int
+	}{
+		{"RightTop", 20, 40, 15, 5},
+		{"BottomRight", 40, 20, 35, 15},
+		{"LeftBottom", 20, 40, 5, 35},
+	}
+	for _, test := range tests {
+		// a 40x20 white image with a red 10x10 block in its top left corner
+		image, _ := NewCanvas(40, 20, "white")
+		block, _ := NewCanvas(10, 10, "red")
+		assert.T(t, image.Composite(block, CopyCompositeOp, NorthWestGravity, 0, 0) == nil)
+		err = image.setOrientation(test.orientation)
+		assert.T(t, err == nil)
+		assert.Equal(t, test.orientation, image.Orientation())
+		err = image.AutoOrient()
+		assert.T(t, err == nil)
+		assert.Equal(t, "TopLeft", image.Orientation())
+		assert.Equal(t, test.width, image.Width())
+		assert.Equal(t, test.height, image.Height())
+		red, _ := image.ExportPixels(test.x, test.y, 1, 1, "RGB", CharPixel)
+		assert.Equal(t, []byte{255, 0, 0}, red)
+		white, _ := image.ExportPixels(test.width-1-test.x, test.height-1-test.y, 1, 1, "RGB", CharPixel)
+		assert.Equal(t, []byte{255, 255, 255}, white)
+	}
+}
+
+func TestAutoOrientAndStrip(t *testing.T) {
+	image := setupImage(t)
+	assert.T(t, image.setOrientation("RightTop") == nil)
+	err := image.AutoOrientAndStrip()
+	assert.T(t, err == nil)
+	assert.Equal(t, "TopLeft", image.Orientation())
+	assert.Equal(t, 552, image.Width())
+	assert.Equal(t, 600, image.Height())
+
+	_, err = setupImage(t).ToBlobWith("jpg", &EncodeOptions{AutoOrient: true, Strip: true})
+	assert.T(t, err == nil)
+}
+
 func TestProgressive(t *testing.T) {
 	image := setupImage(t)
 	image.Progressive()
@@ -128,6 +494,24 @@ func TestDestroy(t *testing.T) {
 	assert.T(t, image.ImageInfo == nil)
 }
 
+func TestClone(t *testing.T) {
+	image := setupImage(t)
+	duplicate, err := image.Clone()
+	assert.T(t, err == nil)
+	assert.T(t, duplicate.Image != image.Image)
+	assert.T(t, duplicate.ImageInfo != image.ImageInfo)
+	err = duplicate.Resize("100x100!")
+	assert.T(t, err == nil)
+	assert.Equal(t, 100, duplicate.Width())
+	assert.Equal(t, 600, image.Width())
+	assert.T(t, duplicate.Destroy() == nil)
+
+	bytes, err := image.ToBlob("png")
+	assert.T(t, err == nil)
+	assert.Equal(t, 437198, len(bytes))
+	assert.T(t, image.Destroy() == nil)
+}
+
 func TestResize(t *testing.T) {
 	image := setupImage(t)
 	err := image.Resize("100x100!")
@@ -140,6 +524,51 @@ func TestResize(t *testing.T) {
 	assert.T(t, err != nil)
 }
 
+func TestResizeWithOptions(t *testing.T) {
+	methods := []ResizeMethod{DefaultResize, FilterResize, ThumbnailResize, SampleResize, ScaleResize, AdaptiveResize}
+	for _, method := range methods {
+		image := setupImage(t)
+		err := image.ResizeWithOptions("100x100!", &ResizeOptions{Method: method})
+		assert.T(t, err == nil)
+		assert.Equal(t, 100, image.Width())
+		assert.Equal(t, 100, image.Height())
+	}
+
+	image := setupImage(t)
+	err := image.ResizeWithOptions("300x300", &ResizeOptions{Method: FilterResize, Filter: "Lanczos", Blur: 0.9})
+	assert.T(t, err == nil)
+	assert.Equal(t, 300, image.Width())
+
+	image = setupImage(t)
+	filter, blur := image.Image.filter, image.Image.blur
+	err = image.ResizeWithOptions("300x300", &ResizeOptions{Method: ThumbnailResize, Filter: "Mitchell", Blur: 0.9})
+	assert.T(t, err == nil)
+	assert.Equal(t, 300, image.Width())
+	// the filter only applies to this resize
+	assert.Equal(t, filter, image.Image.filter)
+	assert.Equal(t, blur, image.Image.blur)
+
+	image = setupImage(t)
+	err = image.ResizeWithOptions("300x300", nil)
+	assert.T(t, err == nil)
+	assert.Equal(t, 300, image.Width())
+
+	image = setupImage(t)
+	err = image.ResizeWithOptions("300x300", &ResizeOptions{Method: LiquidResize})
+	if err == nil {
+		assert.Equal(t, 300, image.Width())
+	}
+
+	image = setupImage(t)
+	err = image.ResizeWithOptions("300x300", &ResizeOptions{Filter: "blurgh"})
+	assert.T(t, err != nil)
+	err = image.ResizeWithOptions("300x300", &ResizeOptions{Blur: -1})
+	assert.T(t, err != nil)
+	err = image.ResizeWithOptions("300x300", &ResizeOptions{Method: ResizeMethod(42)})
+	assert.T(t, err != nil)
+	assert.Equal(t, 600, image.Width())
+}
+
 func TestPDFResize(t *testing.T) {
 	filename := "test/heart_original.pdf"
 	source, _ := ioutil.ReadFile(filename)
@@ -170,41 +599,587 @@ func TestCrop(t *testing.T) {
 	assert.T(t, err != nil)
 }
 
-func TestShadow(t *testing.T) {
+func TestCropWithGravity(t *testing.T) {
+	gravities := []Gravity{NorthWestGravity, NorthGravity, NorthEastGravity, WestGravity, CenterGravity,
+		EastGravity, SouthWestGravity, SouthGravity, SouthEastGravity}
+	for _, gravity := range gravities {
+		image := setupImage(t)
+		err := image.CropWithGravity("200x200", gravity)
+		assert.T(t, err == nil)
+		assert.Equal(t, 200, image.Width())
+		assert.Equal(t, 200, image.Height())
+	}
+
 	image := setupImage(t)
-	err := image.Shadow("#000", 75, 2, 0, 0)
-	assert.T(t, err == nil)
+	err := image.CropWithGravity("blurgh", CenterGravity)
+	assert.T(t, err != nil)
 }
 
-func TestFillBackgroundColor(t *testing.T) {
+func TestThumbnail(t *testing.T) {
 	image := setupImage(t)
-	err := image.FillBackgroundColor("#CCC")
+	err := image.Thumbnail(200, 100, FitThumbnail)
 	assert.T(t, err == nil)
-}
+	assert.Equal(t, 109, image.Width())
+	assert.Equal(t, 100, image.Height())
 
-func TestSeparateAlphaChannel(t *testing.T) {
-	image := setupImage(t)
-	err := image.SeparateAlphaChannel()
+	image = setupImage(t)
+	err = image.Thumbnail(200, 100, FillThumbnail)
 	assert.T(t, err == nil)
-	assert.T(t, image != nil)
+	assert.Equal(t, 200, image.Width())
+	assert.Equal(t, 100, image.Height())
+
+	image = setupImage(t)
+	err = image.Thumbnail(200, 100, PadThumbnail)
+	assert.T(t, err == nil)
+	assert.Equal(t, 200, image.Width())
+	assert.Equal(t, 100, image.Height())
+
+	image = setupImage(t)
+	err = image.Thumbnail(0, 100, FitThumbnail)
+	assert.T(t, err != nil)
+	err = image.Thumbnail(200, 100, ThumbnailMode(42))
+	assert.T(t, err != nil)
 }
 
-func TestNegateImage(t *testing.T) {
+func TestSmartCrop(t *testing.T) {
 	image := setupImage(t)
-	err := image.Negate()
+	geometry, err := image.SmartCrop(200, 200)
 	assert.T(t, err == nil)
-	assert.T(t, image != nil)
+	assert.T(t, geometry != nil)
+	assert.Equal(t, 552, geometry.Width)
+	assert.Equal(t, 552, geometry.Height)
+	assert.T(t, geometry.Xoffset >= 0 && geometry.Xoffset <= 48)
+	assert.Equal(t, 0, geometry.Yoffset)
+	assert.Equal(t, 200, image.Width())
+	assert.Equal(t, 200, image.Height())
+
+	// a flat image with all of its detail on the right hand side
+	pixels := make([]byte, 400*100)
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 400; x++ {
+			pixels[y*400+x] = 255
+			if x >= 300 && (x/4+y/4)%2 == 0 {
+				pixels[y*400+x] = 0
+			}
+		}
+	}
+	image, err = NewFromPixels(400, 100, "I", CharPixel, pixels)
+	assert.T(t, err == nil)
+	geometry, err = image.SmartCrop(50, 50)
+	assert.T(t, err == nil)
+	assert.Equal(t, 100, geometry.Width)
+	assert.T(t, geometry.Xoffset >= 250)
+	assert.Equal(t, 50, image.Width())
+
+	_, err = image.SmartCrop(0, 50)
+	assert.T(t, err != nil)
 }
 
-func TestToBlob(t *testing.T) {
+func TestCropAtFocalPoint(t *testing.T) {
 	image := setupImage(t)
-	bytes, err := image.ToBlob("png")
+	geometry, err := image.CropAtFocalPoint(200, 100, 0.5, 0.5)
 	assert.T(t, err == nil)
-	assert.T(t, bytes != nil)
-	assert.T(t, len(bytes) > 0)
+	assert.Equal(t, 200, geometry.Width)
+	assert.Equal(t, 100, geometry.Height)
+	assert.Equal(t, 200, geometry.Xoffset)
+	assert.Equal(t, 226, geometry.Yoffset)
+	assert.Equal(t, 200, image.Width())
+	assert.Equal(t, 100, image.Height())
+
+	// the region is kept inside the image
+	image = setupImage(t)
+	geometry, err = image.CropAtFocalPoint(200, 100, 0.95, 0.0)
+	assert.T(t, err == nil)
+	assert.Equal(t, 400, geometry.Xoffset)
+	assert.Equal(t, 0, geometry.Yoffset)
+
+	image = setupImage(t)
+	geometry, err = image.CropAtFocalPoint(1000, 1000, 0.2, 0.2)
+	assert.T(t, err == nil)
+	assert.Equal(t, 600, geometry.Width)
+	assert.Equal(t, 552, geometry.Height)
+
+	image = setupImage(t)
+	_, err = image.CropAtFocalPoint(200, 100, 1.5, 0.5)
+	assert.T(t, err != nil)
+	assert.Equal(t, 600, image.Width())
+}
+
+func TestThumbnailAtFocalPoint(t *testing.T) {
+	image := setupImage(t)
+	geometry, err := image.ThumbnailAtFocalPoint(100, 100, 0.0, 0.5)
+	assert.T(t, err == nil)
+	assert.Equal(t, 552, geometry.Width)
+	assert.Equal(t, 552, geometry.Height)
+	assert.Equal(t, 0, geometry.Xoffset)
+	assert.Equal(t, 100, image.Width())
+	assert.Equal(t, 100, image.Height())
+
+	image = setupImage(t)
+	geometry, err = image.ThumbnailAtFocalPoint(300, 100, 0.5, 1.0)
+	assert.T(t, err == nil)
+	assert.Equal(t, 600, geometry.Width)
+	assert.Equal(t, 200, geometry.Height)
+	assert.Equal(t, 352, geometry.Yoffset)
+	assert.Equal(t, 300, image.Width())
+	assert.Equal(t, 100, image.Height())
+}
+
+func TestExtent(t *testing.T) {
+	image := setupImage(t)
+	err := image.Extent("800x800", CenterGravity, "white")
+	assert.T(t, err == nil)
+	assert.Equal(t, 800, image.Width())
+	assert.Equal(t, 800, image.Height())
+	corner, _ := image.ExportPixels(0, 0, 1, 1, "RGBA", CharPixel)
+	assert.Equal(t, []byte{255, 255, 255, 255}, corner)
+
+	image = setupImage(t)
+	err = image.Extent("800x600", SouthEastGravity, "none")
+	assert.T(t, err == nil)
+	assert.Equal(t, 800, image.Width())
+	assert.Equal(t, 600, image.Height())
+	corner, _ = image.ExportPixels(0, 0, 1, 1, "RGBA", CharPixel)
+	assert.Equal(t, byte(0), corner[3])
+
+	image = setupImage(t)
+	err = image.Extent("200x200", CenterGravity, "white")
+	assert.T(t, err == nil)
+	assert.Equal(t, 200, image.Width())
+	assert.Equal(t, 200, image.Height())
+
+	image, _ = NewCanvas(100, 50, "white")
+	background, matte := image.Image.background_color, image.Image.matte
+	err = image.Extent("800x800", CenterGravity, "notacolor")
+	assert.T(t, err != nil)
+	assert.Equal(t, 100, image.Width())
+	assert.Equal(t, background, image.Image.background_color)
+	assert.Equal(t, matte, image.Image.matte)
+	err = image.Extent("200x200", CenterGravity, "none")
+	assert.T(t, err == nil)
+	assert.Equal(t, 200, image.Width())
+
+	image = setupImage(t)
+	err = image.Extent("blurgh", CenterGravity, "white")
+	assert.T(t, err != nil)
+	assert.Equal(t, 600, image.Width())
+}
+
+func TestComposite(t *testing.T) {
+	image, _ := NewCanvas(100, 100, "white")
+	overlay, _ := NewCanvas(10, 10, "#F00")
+	err := image.Composite(overlay, OverCompositeOp, SouthEastGravity, 0, 0)
+	assert.T(t, err == nil)
+	assert.Equal(t, 100, image.Width())
+	pixel, _ := image.ExportPixels(95, 95, 1, 1, "RGB", CharPixel)
+	assert.Equal(t, []byte{255, 0, 0}, pixel)
+	pixel, _ = image.ExportPixels(0, 0, 1, 1, "RGB", CharPixel)
+	assert.Equal(t, []byte{255, 255, 255}, pixel)
+
+	err = image.Composite(overlay, MultiplyCompositeOp, NorthWestGravity, 5, 5)
+	assert.T(t, err == nil)
+	pixel, _ = image.ExportPixels(5, 5, 1, 1, "RGB", CharPixel)
+	assert.Equal(t, []byte{255, 0, 0}, pixel)
+
+	err = image.Composite(setupImage(t), ScreenCompositeOp, CenterGravity, 0, 0)
+	assert.T(t, err == nil)
+
+	err = image.Composite(nil, OverCompositeOp, CenterGravity, 0, 0)
+	assert.T(t, err != nil)
+	err = image.Dissolve(nil, 50, CenterGravity, 0, 0)
+	assert.T(t, err != nil)
+}
+
+func TestDissolve(t *testing.T) {
+	image, _ := NewCanvas(100, 100, "white")
+	overlay, _ := NewCanvas(10, 10, "black")
+	err := image.Dissolve(overlay, 50, CenterGravity, 0, 0)
+	assert.T(t, err == nil)
+	pixel, _ := image.ExportPixels(50, 50, 1, 1, "I", CharPixel)
+	assert.T(t, pixel[0] > 100 && pixel[0] < 155)
+
+	err = image.Dissolve(overlay, 150, CenterGravity, 0, 0)
+	assert.T(t, err != nil)
+}
+
+func TestWatermark(t *testing.T) {
+	image := setupImage(t)
+	logo, _ := NewCanvas(50, 20, "blue")
+	err := image.Watermark(logo, 0.25, 100, SouthEastGravity, false)
+	assert.T(t, err == nil)
+	pixel, _ := image.ExportPixels(599, 551, 1, 1, "RGB", CharPixel)
+	assert.Equal(t, []byte{0, 0, 255}, pixel)
+	assert.Equal(t, 50, logo.Width())
+
+	image, _ = NewCanvas(100, 100, "white")
+	err = image.Watermark(logo, 0, 100, CenterGravity, true)
+	assert.T(t, err == nil)
+	pixel, _ = image.ExportPixels(99, 99, 1, 1, "RGB", CharPixel)
+	assert.Equal(t, []byte{0, 0, 255}, pixel)
+
+	err = image.Watermark(logo, 0.25, 30, CenterGravity, true)
+	assert.T(t, err == nil)
+	err = image.Watermark(logo, -1, 100, CenterGravity, false)
+	assert.T(t, err != nil)
+	err = image.Watermark(logo, 0.25, 0, CenterGravity, false)
+	assert.T(t, err != nil)
+	err = image.Watermark(nil, 0.25, 100, CenterGravity, false)
+	assert.T(t, err != nil)
+}
+
+func TestAnnotate(t *testing.T) {
+	image, _ := NewCanvas(200, 100, "white")
+	err := image.Annotate("Hello", &TextOptions{PointSize: 32, Color: "#F00", Gravity: CenterGravity})
+	assert.T(t, err == nil)
+	assert.Equal(t, 200, image.Width())
+	assert.Equal(t, 100, image.Height())
+	corner, _ := image.ExportPixels(0, 0, 1, 1, "RGB", CharPixel)
+	assert.Equal(t, []byte{255, 255, 255}, corner)
+
+	image = setupImage(t)
+	err = image.Annotate("A longer title that needs to wrap", &TextOptions{
+		PointSize:   24,
+		Color:       "white",
+		StrokeColor: "black",
+		StrokeWidth: 1,
+		Gravity:     SouthWestGravity,
+		XOffset:     10,
+		YOffset:     -5,
+		WrapWidth:   200,
+	})
+	assert.T(t, err == nil)
+
+	err = image.Annotate("Hello", nil)
+	assert.T(t, err == nil)
+	err = image.Annotate("Hello", &TextOptions{Color: "notacolor"})
+	assert.T(t, err != nil)
+	blank, _ := NewCanvas(200, 100, "white")
+	before, _ := blank.ExportPixels(0, 0, 200, 100, "RGB", CharPixel)
+	err = blank.Annotate("Hello", &TextOptions{Font: "test/nonexistent.ttf", PointSize: 48, Gravity: CenterGravity})
+	assert.T(t, err != nil)
+	after, _ := blank.ExportPixels(0, 0, 200, 100, "RGB", CharPixel)
+	assert.T(t, bytes.Equal(before, after))
+}
+
+func TestMeasureText(t *testing.T) {
+	image, _ := NewCanvas(200, 100, "white")
+	small, err := image.MeasureText("Hello", &TextOptions{PointSize: 12})
+	assert.T(t, err == nil)
+	assert.T(t, small.Width > 0)
+	assert.T(t, small.Height > 0)
+	large, err := image.MeasureText("Hello", &TextOptions{PointSize: 48})
+	assert.T(t, err == nil)
+	assert.T(t, large.Width > small.Width)
+	assert.T(t, large.Ascent > small.Ascent)
+
+	text := "A longer title that needs to wrap onto several lines"
+	line, err := image.MeasureText(text, &TextOptions{PointSize: 24})
+	assert.T(t, err == nil)
+	wrapped, err := image.MeasureText(text, &TextOptions{PointSize: 24, WrapWidth: 150})
+	assert.T(t, err == nil)
+	assert.T(t, wrapped.Width <= 150)
+	assert.T(t, wrapped.Width < line.Width)
+	assert.T(t, wrapped.Height > line.Height)
+
+	_, err = image.MeasureText("Hello", &TextOptions{Font: "test/nonexistent.ttf"})
+	assert.T(t, err != nil)
+}
+
+func TestShadow(t *testing.T) {
+	image := setupImage(t)
+	err := image.Shadow("#000", 75, 2, 0, 0)
+	assert.T(t, err == nil)
+}
+
+func TestFillBackgroundColor(t *testing.T) {
+	image := setupImage(t)
+	err := image.FillBackgroundColor("#CCC")
+	assert.T(t, err == nil)
+}
+
+func TestSeparateAlphaChannel(t *testing.T) {
+	image := setupImage(t)
+	err := image.SeparateAlphaChannel()
+	assert.T(t, err == nil)
+	assert.T(t, image != nil)
+}
+
+func TestNegateImage(t *testing.T) {
+	image := setupImage(t)
+	err := image.Negate()
+	assert.T(t, err == nil)
+	assert.T(t, image != nil)
+}
+
+func TestRotate(t *testing.T) {
+	image := setupImage(t)
+	err := image.Rotate(90, "none")
+	assert.T(t, err == nil)
+	assert.Equal(t, 552, image.Width())
+	assert.Equal(t, 600, image.Height())
+
+	image = setupImage(t)
+	err = image.Rotate(45, "#FFF")
+	assert.T(t, err == nil)
+	assert.T(t, image.Width() > 600)
+	assert.T(t, image.Height() > 552)
+
+	image, _ = NewCanvas(100, 50, "white")
+	background, matte := image.Image.background_color, image.Image.matte
+	err = image.Rotate(45, "notacolor")
+	assert.T(t, err != nil)
+	assert.Equal(t, 100, image.Width())
+	assert.Equal(t, background, image.Image.background_color)
+	assert.Equal(t, matte, image.Image.matte)
+}
+
+func TestFlipFlop(t *testing.T) {
+	image, err := NewLinearGradient(10, 20, "white", "black")
+	assert.T(t, err == nil)
+	top, _ := image.ExportPixels(0, 0, 1, 1, "I", CharPixel)
+	err = image.Flip()
+	assert.T(t, err == nil)
+	flipped, _ := image.ExportPixels(0, 0, 1, 1, "I", CharPixel)
+	assert.T(t, top[0] > flipped[0])
+
+	err = image.Flop()
+	assert.T(t, err == nil)
+	assert.Equal(t, 10, image.Width())
+	assert.Equal(t, 20, image.Height())
+}
+
+func TestTransposeTransverse(t *testing.T) {
+	image := setupImage(t)
+	err := image.Transpose()
+	assert.T(t, err == nil)
+	assert.Equal(t, 552, image.Width())
+	assert.Equal(t, 600, image.Height())
+
+	err = image.Transverse()
+	assert.T(t, err == nil)
+	assert.Equal(t, 600, image.Width())
+	assert.Equal(t, 552, image.Height())
+}
+
+func TestTrim(t *testing.T) {
+	// a white image with a grey 20x10 block at 30,40
+	pixels := make([]byte, 100*80)
+	for i := range pixels {
+		pixels[i] = 255
+		if x, y := i%100, i/100; x >= 30 && x < 50 && y >= 40 && y < 50 {
+			pixels[i] = 128
+		}
+	}
+	// a few nearly white pixels that only a fuzzy trim ignores
+	pixels[5*100+5] = 250
+	image, err := NewFromPixels(100, 80, "I", CharPixel, pixels)
+	assert.T(t, err == nil)
+	geometry, err := image.Trim(5)
+	assert.T(t, err == nil)
+	assert.Equal(t, 20, geometry.Width)
+	assert.Equal(t, 10, geometry.Height)
+	assert.Equal(t, 30, geometry.Xoffset)
+	assert.Equal(t, 40, geometry.Yoffset)
+	assert.Equal(t, 20, image.Width())
+	assert.Equal(t, 10, image.Height())
+
+	image, _ = NewFromPixels(100, 80, "I", CharPixel, pixels)
+	geometry, err = image.Trim(0)
+	assert.T(t, err == nil)
+	assert.Equal(t, 5, geometry.Xoffset)
+	assert.Equal(t, 5, geometry.Yoffset)
+
+	// a transparent 60x40 image, with differently colored transparent pixels,
+	// around an opaque red 20x10 block at 15,20
+	pixels = make([]byte, 60*40*4)
+	for i := 0; i < 60*40; i++ {
+		pixel := []byte{byte(i), byte(i / 3), 255, 0}
+		if x, y := i%60, i/60; x >= 15 && x < 35 && y >= 20 && y < 30 {
+			pixel = []byte{255, 0, 0, 255}
+		}
+		copy(pixels[4*i:], pixel)
+	}
+	image, err = NewFromPixels(60, 40, "RGBA", CharPixel, pixels)
+	assert.T(t, err == nil)
+	geometry, err = image.Trim(0)
+	assert.T(t, err == nil)
+	assert.Equal(t, 20, geometry.Width)
+	assert.Equal(t, 10, geometry.Height)
+	assert.Equal(t, 15, geometry.Xoffset)
+	assert.Equal(t, 20, geometry.Yoffset)
+	assert.Equal(t, 20, image.Width())
+	assert.Equal(t, 10, image.Height())
+
+	image = setupImage(t)
+	geometry, err = image.Trim(0)
+	assert.T(t, err == nil)
+	assert.Equal(t, geometry.Width, image.Width())
+	assert.T(t, image.Width() <= 600)
+
+	image, _ = NewCanvas(50, 50, "red")
+	_, err = image.Trim(0)
+	assert.T(t, err != nil)
+	_, err = image.Trim(101)
+	assert.T(t, err != nil)
+}
+
+func TestToBlob(t *testing.T) {
+	image := setupImage(t)
+	bytes, err := image.ToBlob("png")
+	assert.T(t, err == nil)
+	assert.T(t, bytes != nil)
+	assert.T(t, len(bytes) > 0)
 	assert.Equal(t, 437198, len(bytes))
 }
 
+type failingWriter struct {
+	limit int
+}
+
+func (w *failingWriter) Write(p []byte) (n int, err error) {
+	if len(p) > w.limit {
+		n = w.limit
+		w.limit = 0
+		return n, errors.New("writer is full")
+	}
+	w.limit -= len(p)
+	return len(p), nil
+}
+
+func TestEncodeTo(t *testing.T) {
+	image := setupImage(t)
+	var buffer bytes.Buffer
+	n, err := image.EncodeTo(&buffer, "png")
+	assert.T(t, err == nil)
+	assert.T(t, n > 0)
+	assert.Equal(t, n, int64(buffer.Len()))
+
+	blob, err := image.ToBlob("png")
+	assert.T(t, err == nil)
+	assert.Equal(t, len(blob), buffer.Len())
+
+	_, err = image.EncodeTo(&failingWriter{1024}, "png")
+	assert.T(t, err != nil)
+	writeErr, ok := err.(*WriteError)
+	assert.T(t, ok)
+	assert.Equal(t, int64(1024), writeErr.Written)
+}
+
+func TestWriteTo(t *testing.T) {
+	image := setupImage(t)
+	var buffer bytes.Buffer
+	n, err := image.WriteTo(&buffer)
+	assert.T(t, err == nil)
+	assert.Equal(t, n, int64(buffer.Len()))
+	reloaded, err := NewFromBlob(buffer.Bytes(), "png")
+	assert.T(t, err == nil)
+	assert.Equal(t, "PNG", reloaded.Type())
+
+	canvas, _ := NewCanvas(10, 10, "white")
+	_, err = canvas.WriteTo(&buffer)
+	assert.T(t, err != nil)
+	_, err = canvas.EncodeTo(&buffer, "png")
+	assert.T(t, err == nil)
+}
+
+func TestEncodeToOffset(t *testing.T) {
+	// tiff is written with seeks, which have to stay after what is already in the file
+	file, err := ioutil.TempFile("", "encode")
+	assert.T(t, err == nil)
+	defer os.Remove(file.Name())
+	defer file.Close()
+	_, err = file.Write([]byte("HEADER"))
+	assert.T(t, err == nil)
+	image := setupImage(t)
+	n, err := image.EncodeTo(file, "tiff")
+	assert.T(t, err == nil)
+	assert.T(t, n > 0)
+	written, _ := ioutil.ReadFile(file.Name())
+	assert.Equal(t, "HEADER", string(written[:6]))
+	reloaded, err := NewFromBlob(written[6:], "tiff")
+	assert.T(t, err == nil)
+	assert.Equal(t, 600, reloaded.Width())
+}
+
+func TestToBlobWith(t *testing.T) {
+	image := setupImage(t)
+	high, err := image.ToBlobWith("jpg", &EncodeOptions{Quality: 95, SamplingFactor: "4:4:4"})
+	assert.T(t, err == nil)
+	low, err := image.ToBlobWith("jpg", &EncodeOptions{Quality: 20, Interlace: "Plane", Strip: true})
+	assert.T(t, err == nil)
+	assert.T(t, len(low) < len(high))
+
+	_, err = image.ToBlobWith("png", &EncodeOptions{PNGCompressionLevel: 9, PNGFilter: "adaptive", Depth: 8})
+	assert.T(t, err == nil)
+
+	_, err = image.ToBlobWith("jpg", nil)
+	assert.T(t, err == nil)
+
+	// encoding with options does not change the image
+	assert.Equal(t, "PNG", image.Type())
+	bytes, err := image.ToBlob("png")
+	assert.T(t, err == nil)
+	assert.Equal(t, 437198, len(bytes))
+
+	_, err = image.ToBlobWith("png", &EncodeOptions{SamplingFactor: "4:2:0"})
+	assert.T(t, err != nil)
+	_, err = image.ToBlobWith("jpg", &EncodeOptions{WebPLossless: true})
+	assert.T(t, err != nil)
+	_, err = image.ToBlobWith("jpg", &EncodeOptions{Quality: 101})
+	assert.T(t, err != nil)
+	_, err = image.ToBlobWith("jpg", &EncodeOptions{Interlace: "blurgh"})
+	assert.T(t, err != nil)
+	_, err = image.ToBlobWith("png", &EncodeOptions{PNGFilter: "blurgh"})
+	assert.T(t, err != nil)
+	_, err = image.ToBlobWith("png", &EncodeOptions{Depth: 7})
+	assert.T(t, err != nil)
+}
+
+func TestToFileWith(t *testing.T) {
+	image := setupImage(t)
+	filename := "test/test_encode_options.jpg"
+	os.Remove(filename)
+	err := image.ToFileWith(filename, &EncodeOptions{Quality: 50, Interlace: "Plane", SamplingFactor: "4:2:0"})
+	assert.T(t, err == nil)
+	_, err = os.Stat(filename)
+	assert.T(t, err == nil)
+
+	err = image.ToFileWith("test/test_encode_options.png", &EncodeOptions{WebPMethod: 4})
+	assert.T(t, err != nil)
+}
+
+func TestRenditions(t *testing.T) {
+	image := setupImage(t)
+	renditions := image.Renditions([]RenditionSpec{
+		{Geometry: "300x300", Format: "jpg"},
+		{Geometry: "300x300", Format: "png"},
+		{Name: "small", Geometry: "100x100", Format: "jpg", Options: &EncodeOptions{Quality: 60}},
+		{Name: "original", Geometry: "100%", Format: "png"},
+		{Name: "bad", Geometry: "blurgh", Format: "jpg"},
+		{Name: "bad options", Geometry: "100x100", Format: "png", Options: &EncodeOptions{SamplingFactor: "4:2:0"}},
+		{Geometry: "200x200", Format: "jpg", Options: &EncodeOptions{Quality: 90}},
+		{Geometry: "200x200", Format: "jpg", Options: &EncodeOptions{Quality: 30}},
+	})
+	assert.Equal(t, 7, len(renditions))
+	rendition := renditions["300x300.jpg"]
+	assert.T(t, rendition.Err == nil)
+	assert.T(t, len(rendition.Blob) > 0)
+	assert.Equal(t, 300, rendition.Width)
+	assert.Equal(t, 276, rendition.Height)
+	assert.T(t, renditions["300x300.png"].Err == nil)
+	assert.Equal(t, 100, renditions["small"].Width)
+	assert.T(t, renditions["small"].Err == nil)
+	assert.Equal(t, 600, renditions["original"].Width)
+	assert.T(t, renditions["bad"].Err != nil)
+	assert.T(t, renditions["bad options"].Err != nil)
+	assert.T(t, renditions["200x200.jpg"].Err != nil)
+
+	// the source image is left untouched
+	assert.Equal(t, 600, image.Width())
+	assert.Equal(t, 552, image.Height())
+	assert.Equal(t, "PNG", image.Type())
+}
+
 func TestToFile(t *testing.T) {
 	image := setupImage(t)
 	filename := "test/test_out.png"
@@ -219,6 +1194,108 @@ func TestToFile(t *testing.T) {
 	assert.Equal(t, (int64)(437198), stat.Size())
 }
 
+func TestNewFromPixels(t *testing.T) {
+	rgb := make([]byte, 40*30*3)
+	for i := 0; i < len(rgb); i += 3 {
+		rgb[i] = 255
+	}
+	image, err := NewFromPixels(40, 30, "RGB", CharPixel, rgb)
+	assert.T(t, err == nil)
+	assert.T(t, image != nil)
+	assert.Equal(t, 40, image.Width())
+	assert.Equal(t, 30, image.Height())
+	err = image.Resize("20x15")
+	assert.T(t, err == nil)
+
+	gray := make([]byte, 40*30*2)
+	image, err = NewFromPixels(40, 30, "I", ShortPixel, gray)
+	assert.T(t, err == nil)
+	assert.Equal(t, 40, image.Width())
+
+	image, err = NewFromPixels(40, 30, "RGBA", FloatPixel, make([]byte, 40*30*4*4))
+	assert.T(t, err == nil)
+	assert.Equal(t, 30, image.Height())
+
+	image, err = NewFromPixels(40, 30, "RGB", CharPixel, rgb[:100])
+	assert.T(t, err != nil)
+	assert.T(t, image == nil)
+}
+
+func TestExportPixels(t *testing.T) {
+	image, err := NewCanvas(10, 10, "#F00")
+	assert.T(t, err == nil)
+	data, err := image.ExportPixels(2, 2, 4, 3, "RGB", CharPixel)
+	assert.T(t, err == nil)
+	assert.Equal(t, 4*3*3, len(data))
+	assert.Equal(t, []byte{255, 0, 0}, data[:3])
+
+	data, err = image.ExportPixels(0, 0, 10, 10, "I", ShortPixel)
+	assert.T(t, err == nil)
+	assert.Equal(t, 10*10*2, len(data))
+
+	data, err = image.ExportPixels(0, 0, 10, 10, "RGBA", FloatPixel)
+	assert.T(t, err == nil)
+	assert.Equal(t, 10*10*4*4, len(data))
+
+	_, err = image.ExportPixels(0, 0, 10, 10, "", CharPixel)
+	assert.T(t, err != nil)
+	_, err = image.ExportPixels(-1, 0, 5, 5, "RGB", CharPixel)
+	assert.T(t, err != nil)
+	_, err = image.ExportPixels(0, 6, 5, 5, "RGB", CharPixel)
+	assert.T(t, err != nil)
+	_, err = image.ExportPixels(6, 0, 5, 5, "RGB", CharPixel)
+	assert.T(t, err != nil)
+}
+
+func TestToGoImage(t *testing.T) {
+	img, err := setupImage(t).ToGoImage()
+	assert.T(t, err == nil)
+	assert.T(t, img != nil)
+	assert.Equal(t, 600, img.Bounds().Dx())
+	assert.Equal(t, 552, img.Bounds().Dy())
+}
+
+func TestNewFromGoImage(t *testing.T) {
+	img, err := setupImage(t).ToGoImage()
+	assert.T(t, err == nil)
+	image, err := NewFromGoImage(img)
+	assert.T(t, err == nil)
+	assert.T(t, image != nil)
+	assert.Equal(t, 600, image.Width())
+	assert.Equal(t, 552, image.Height())
+	_, err = image.ToBlob("png")
+	assert.T(t, err == nil)
+
+	rgba := goimage.NewRGBA(goimage.Rect(10, 10, 60, 40))
+	draw.Draw(rgba, rgba.Bounds(), &goimage.Uniform{color.RGBA{255, 0, 0, 255}}, goimage.Point{}, draw.Src)
+	image, err = NewFromGoImage(rgba)
+	assert.T(t, err == nil)
+	assert.Equal(t, 50, image.Width())
+	assert.Equal(t, 30, image.Height())
+	back, err := image.ToGoImage()
+	assert.T(t, err == nil)
+	r, g, b, a := back.At(0, 0).RGBA()
+	assert.Equal(t, uint32(0xffff), r)
+	assert.Equal(t, uint32(0), g)
+	assert.Equal(t, uint32(0), b)
+	assert.Equal(t, uint32(0xffff), a)
+
+	// the top rows of a larger image share its pixel buffer
+	parent := goimage.NewNRGBA(goimage.Rect(0, 0, 50, 40))
+	draw.Draw(parent, goimage.Rect(0, 0, 50, 10), &goimage.Uniform{color.NRGBA{0, 0, 255, 255}}, goimage.Point{}, draw.Src)
+	image, err = NewFromGoImage(parent.SubImage(goimage.Rect(0, 0, 50, 10)))
+	assert.T(t, err == nil)
+	assert.Equal(t, 50, image.Width())
+	assert.Equal(t, 10, image.Height())
+	back, err = image.ToGoImage()
+	assert.T(t, err == nil)
+	assert.Equal(t, color.NRGBA{0, 0, 255, 255}, back.At(49, 9))
+
+	image, err = NewFromGoImage(goimage.NewRGBA(goimage.Rect(0, 0, 0, 0)))
+	assert.T(t, err != nil)
+	assert.T(t, image == nil)
+}
+
 func TestType(t *testing.T) {
 	image := setupImage(t)
 	assert.Equal(t, "PNG", image.Type())
@@ -242,6 +1319,217 @@ func TestSetProperty(t *testing.T) {
 	assert.Equal(t, "4:4:4", factor)
 }
 
+func setupList(t *testing.T) (list *MagickImageList) {
+	list = NewList()
+	for _, color := range []string{"red", "green", "blue"} {
+		frame, err := NewCanvas(100, 80, color)
+		assert.T(t, err == nil)
+		err = list.Append(frame)
+		assert.T(t, err == nil)
+		frame.Destroy()
+	}
+	return
+}
+
+func TestImageList(t *testing.T) {
+	list := setupList(t)
+	defer list.Destroy()
+	assert.Equal(t, 3, list.Len())
+	frame, err := list.Frame(1)
+	assert.T(t, err == nil)
+	assert.Equal(t, 100, frame.Width())
+	frame.Destroy()
+	_, err = list.Frame(3)
+	assert.T(t, err != nil)
+
+	err = list.Each(func(i int, frame *MagickImage) error {
+		return frame.Resize("50x40!")
+	})
+	assert.T(t, err == nil)
+	assert.Equal(t, 3, list.Len())
+	frame, _ = list.Frame(2)
+	assert.Equal(t, 50, frame.Width())
+	assert.Equal(t, 40, frame.Height())
+
+	err = list.Each(func(i int, frame *MagickImage) error {
+		return frame.Resize("blurgh")
+	})
+	assert.T(t, err != nil)
+	assert.Equal(t, 3, list.Len())
+
+	blob, err := list.ToBlob("gif")
+	assert.T(t, err == nil)
+	reloaded, err := NewListFromBlob(blob, "gif")
+	assert.T(t, err == nil)
+	assert.Equal(t, 3, reloaded.Len())
+
+	assert.T(t, NewList().Len() == 0)
+}
+
+func assertFrames(t *testing.T, list *MagickImageList, width, height int) {
+	for i := 0; i < list.Len(); i++ {
+		frame, err := list.Frame(i)
+		assert.T(t, err == nil)
+		// layers of an optimized animation vary in size
+		if width > 0 {
+			assert.Equal(t, width, frame.Width())
+			assert.Equal(t, height, frame.Height())
+		}
+		assert.Equal(t, 20, frame.Delay())
+		assert.Equal(t, 3, frame.Iterations())
+		// the optimizer picks the disposal methods of an animation again
+		assert.T(t, frame.Dispose() != UndefinedDispose)
+		frame.Destroy()
+	}
+}
+
+// setupAnimation returns a GIF animation of a red block moving over a white
+// background, optimized into layers that only hold the moving block
+func setupAnimation(t *testing.T) (list *MagickImageList) {
+	list = NewList()
+	for i := 0; i < 3; i++ {
+		frame, _ := NewCanvas(100, 80, "white")
+		block, _ := NewCanvas(20, 20, "red")
+		assert.T(t, frame.Composite(block, CopyCompositeOp, NorthWestGravity, 10+30*i, 30) == nil)
+		assert.T(t, frame.SetProperty("delay", "20") == nil)
+		assert.T(t, frame.SetProperty("loop", "3") == nil)
+		list.Append(frame)
+		frame.Destroy()
+		block.Destroy()
+	}
+	blob, err := list.ToBlob("gif")
+	assert.T(t, err == nil)
+	list.Destroy()
+	list, err = NewListFromBlob(blob, "gif")
+	assert.T(t, err == nil)
+	assert.T(t, list.Optimize() == nil)
+	blob, err = list.ToBlob("gif")
+	assert.T(t, err == nil)
+	list.Destroy()
+	list, err = NewListFromBlob(blob, "gif")
+	assert.T(t, err == nil)
+	layer, _ := list.Frame(1)
+	assert.T(t, layer.Width() < 100)
+	layer.Destroy()
+	return
+}
+
+func TestLayeredAnimation(t *testing.T) {
+	for _, transform := range []func(list *MagickImageList) error{
+		func(list *MagickImageList) error { return list.Resize("50x40") },
+		func(list *MagickImageList) error { return list.Crop("50x40+25+20") },
+	} {
+		list := setupAnimation(t)
+		assert.Equal(t, 3, list.Len())
+		err := transform(list)
+		assert.T(t, err == nil)
+		assert.Equal(t, 3, list.Len())
+		blob, err := list.ToBlob("gif")
+		assert.T(t, err == nil)
+		list.Destroy()
+		list, err = NewListFromBlob(blob, "gif")
+		assert.T(t, err == nil)
+		assert.Equal(t, 3, list.Len())
+		assertFrames(t, list, 0, 0)
+		first, _ := list.Frame(0)
+		assert.Equal(t, 50, first.Width())
+		assert.Equal(t, 40, first.Height())
+		first.Destroy()
+		// played back every frame is the full size
+		assert.T(t, list.Coalesce() == nil)
+		for i := 0; i < list.Len(); i++ {
+			frame, _ := list.Frame(i)
+			assert.Equal(t, 50, frame.Width())
+			assert.Equal(t, 40, frame.Height())
+			frame.Destroy()
+		}
+		list.Destroy()
+	}
+
+	list := setupAnimation(t)
+	defer list.Destroy()
+	err := list.Resize("50x40")
+	assert.T(t, err == nil)
+	assert.T(t, list.Coalesce() == nil)
+	frame, _ := list.Frame(1)
+	red, _ := frame.ExportPixels(25, 20, 1, 1, "RGB", CharPixel)
+	assert.Equal(t, []byte{255, 0, 0}, red)
+	white, _ := frame.ExportPixels(5, 5, 1, 1, "RGB", CharPixel)
+	assert.Equal(t, []byte{255, 255, 255}, white)
+}
+
+func TestImageListResize(t *testing.T) {
+	list := NewList()
+	for _, color := range []string{"red", "green", "blue"} {
+		frame, _ := NewCanvas(100, 80, color)
+		assert.T(t, frame.SetProperty("delay", "20") == nil)
+		assert.T(t, frame.SetProperty("dispose", "Background") == nil)
+		assert.T(t, frame.SetProperty("loop", "3") == nil)
+		list.Append(frame)
+		frame.Destroy()
+	}
+	assert.T(t, !list.IsAnimation())
+	blob, err := list.ToBlob("gif")
+	assert.T(t, err == nil)
+	list.Destroy()
+	list, err = NewListFromBlob(blob, "gif")
+	assert.T(t, err == nil)
+	defer list.Destroy()
+	assert.T(t, list.IsAnimation())
+	assertFrames(t, list, 100, 80)
+	err = list.Resize("50x40")
+	assert.T(t, err == nil)
+	assert.Equal(t, 3, list.Len())
+	assertFrames(t, list, 50, 40)
+	blob, err = list.ToBlob("gif")
+	assert.T(t, err == nil)
+	reloaded, err := NewListFromBlob(blob, "gif")
+	assert.T(t, err == nil)
+	defer reloaded.Destroy()
+	assert.Equal(t, 3, reloaded.Len())
+	assertFrames(t, reloaded, 50, 40)
+
+	err = list.Resize("blurgh")
+	assert.T(t, err != nil)
+
+	// pages of different sizes are resized independently
+	pages := NewList()
+	defer pages.Destroy()
+	for _, width := range []int{100, 200} {
+		page, _ := NewCanvas(width, 80, "white")
+		pages.Append(page)
+		page.Destroy()
+	}
+	err = pages.Resize("50%")
+	assert.T(t, err == nil)
+	first, _ := pages.Frame(0)
+	second, _ := pages.Frame(1)
+	assert.Equal(t, 50, first.Width())
+	assert.Equal(t, 100, second.Width())
+	assert.Equal(t, 40, second.Height())
+}
+
+func TestImageListCrop(t *testing.T) {
+	list := setupList(t)
+	defer list.Destroy()
+	err := list.Crop("20x20+10+10")
+	assert.T(t, err == nil)
+	assert.Equal(t, 3, list.Len())
+	frame, _ := list.Frame(2)
+	assert.Equal(t, 20, frame.Width())
+	assert.Equal(t, 20, frame.Height())
+}
+
+func TestPDFImageList(t *testing.T) {
+	list, err := NewListFromFile("test/heart_original.pdf")
+	assert.T(t, err == nil)
+	assert.T(t, list.Len() > 0)
+	filename := "test/test_list.gif"
+	os.Remove(filename)
+	err = list.ToFile(filename)
+	assert.T(t, err == nil)
+}
+
 func TestFullStack(t *testing.T) {
 	var err error
 	var filename string
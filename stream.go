@@ -0,0 +1,111 @@
+package magick
+
+/*
+#include <magick/MagickCore.h>
+*/
+import "C"
+import (
+	"io"
+	"runtime/cgo"
+	"unsafe"
+)
+
+// stream connects a Go io.Reader or io.Writer to a MagickCore CustomStreamInfo.
+// It is handed to C as a cgo.Handle and recovered by the exported callbacks below.
+type stream struct {
+	reader  io.Reader
+	writer  io.Writer
+	seeker  io.Seeker
+	base    int64
+	written int64
+	err     error
+}
+
+func newStream(r io.Reader) *stream {
+	s := &stream{reader: r}
+	if seeker, ok := r.(io.Seeker); ok {
+		s.setSeeker(seeker)
+	}
+	return s
+}
+
+// setSeeker makes the stream seekable, with offsets relative to the current position of seeker
+// so a reader or writer that is not at its start is never rewound past it
+func (s *stream) setSeeker(seeker io.Seeker) {
+	base, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return
+	}
+	s.seeker = seeker
+	s.base = base
+}
+
+func newWriteStream(w io.Writer) *stream {
+	s := &stream{writer: w}
+	if seeker, ok := w.(io.Seeker); ok {
+		s.setSeeker(seeker)
+	}
+	return s
+}
+
+func streamFromHandle(user_data unsafe.Pointer) *stream {
+	return cgo.Handle(uintptr(user_data)).Value().(*stream)
+}
+
+//export magickStreamRead
+func magickStreamRead(data *C.uchar, length C.size_t, user_data unsafe.Pointer) C.ssize_t {
+	s := streamFromHandle(user_data)
+	if s.err != nil {
+		return -1
+	}
+	buf := unsafe.Slice((*byte)(unsafe.Pointer(data)), int(length))
+	n, err := io.ReadFull(s.reader, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		s.err = err
+		return -1
+	}
+	return (C.ssize_t)(n)
+}
+
+//export magickStreamWrite
+func magickStreamWrite(data *C.uchar, length C.size_t, user_data unsafe.Pointer) C.ssize_t {
+	s := streamFromHandle(user_data)
+	if s.err != nil {
+		return -1
+	}
+	buf := unsafe.Slice((*byte)(unsafe.Pointer(data)), int(length))
+	n, err := s.writer.Write(buf)
+	s.written += int64(n)
+	if err == nil && n < len(buf) {
+		err = io.ErrShortWrite
+	}
+	if err != nil {
+		s.err = err
+		return -1
+	}
+	return (C.ssize_t)(n)
+}
+
+//export magickStreamSeek
+func magickStreamSeek(offset C.MagickOffsetType, whence C.int, user_data unsafe.Pointer) C.MagickOffsetType {
+	s := streamFromHandle(user_data)
+	target := int64(offset)
+	if int(whence) == io.SeekStart {
+		target += s.base
+	}
+	position, err := s.seeker.Seek(target, int(whence))
+	if err != nil || position < s.base {
+		return -1
+	}
+	return (C.MagickOffsetType)(position - s.base)
+}
+
+//export magickStreamTell
+func magickStreamTell(user_data unsafe.Pointer) C.MagickOffsetType {
+	s := streamFromHandle(user_data)
+	position, err := s.seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return -1
+	}
+	return (C.MagickOffsetType)(position - s.base)
+}